@@ -0,0 +1,202 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/almushel/inertHTML/minify"
+)
+
+// A file copied to the output unchanged
+type asset struct {
+	src, dest string
+}
+
+// Glob patterns for files, as a flag that can be repeated
+// Patterns containing a / match paths from the root of their directory, others match any file or directory name
+type Patterns []string
+
+func (p *Patterns) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *Patterns) Set(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q", pattern)
+	}
+	*p = append(*p, pattern)
+	return nil
+}
+
+func (p *Patterns) Get() any {
+	return []string(*p)
+}
+
+// Minifiers by file type
+var minifiers = map[string]func(string) (string, error){
+	"css":  minify.CSS,
+	"js":   minify.JS,
+	"html": minify.HTML,
+}
+
+// File types by extension
+var fileTypes = map[string]string{
+	".css":  "css",
+	".js":   "js",
+	".mjs":  "js",
+	".html": "html",
+	".htm":  "html",
+}
+
+// Names of file types, as a flag of comma separated names that can be repeated
+type FileTypes []string
+
+func (types *FileTypes) String() string {
+	return strings.Join(*types, ",")
+}
+
+func (types *FileTypes) Set(value string) error {
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := minifiers[name]; !ok {
+			return fmt.Errorf("unknown file type %q", name)
+		}
+		if !slices.Contains(*types, name) {
+			*types = append(*types, name)
+		}
+	}
+	return nil
+}
+
+func (types *FileTypes) Get() any {
+	return []string(*types)
+}
+
+// The minifier for file, if its type is one of types
+func (types FileTypes) minifier(file string) func(string) (string, error) {
+	name := fileTypes[strings.ToLower(filepath.Ext(file))]
+	if !slices.Contains(types, name) {
+		return nil
+	}
+	return minifiers[name]
+}
+
+// Sizes before and after minifying, e.g. 2048 -> 1536 bytes, 25% smaller
+func sizeReport(before, after int) string {
+	saved := 0
+	if before > 0 {
+		saved = (before - after) * 100 / before
+	}
+	return fmt.Sprintf("%d -> %d bytes, %d%% smaller", before, after, saved)
+}
+
+// Whether rel, a slash separated path, or one of its parent directories matches one of patterns
+func (p Patterns) Match(rel string) bool {
+	parts := strings.Split(rel, "/")
+	for _, pattern := range p {
+		for i := range parts {
+			candidate := parts[i]
+			if strings.Contains(pattern, "/") {
+				candidate = strings.Join(parts[:i+1], "/")
+			}
+
+			if ok, _ := path.Match(pattern, candidate); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Whether the file at rel, relative to the directory it is copied from, should be copied
+// Hidden files and directories are skipped, as are files matching an exclude pattern or no include pattern
+func assetIncluded(rel string, flags InertFlags) bool {
+	rel = filepath.ToSlash(rel)
+	for _, part := range strings.Split(rel, "/") {
+		if strings.HasPrefix(part, ".") {
+			return false
+		}
+	}
+
+	if flags.Exclude.Match(rel) {
+		return false
+	}
+	return len(flags.Include) == 0 || flags.Include.Match(rel)
+}
+
+// The static files of the site and its theme, copied to destRoot
+// Files in the site's static directory override theme files with the same path
+func staticAssets(destRoot string, flags InertFlags) ([]asset, error) {
+	var dirs []string
+	if flags.Theme != "" {
+		dirs = append(dirs, filepath.Join(themeDir(flags), staticDir))
+	}
+	dirs = append(dirs, filepath.Join(flags.Root, staticDir))
+
+	var assets []asset
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if errors.Is(err, os.ErrNotExist) && path == dir {
+				return filepath.SkipDir
+			} else if err != nil || d.IsDir() {
+				return err
+			}
+
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			if assetIncluded(rel, flags) {
+				assets = append(assets, asset{src: path, dest: filepath.Join(destRoot, rel)})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return assets, nil
+}
+
+// Assets without those replaced by later assets with the same destination
+func uniqueAssets(assets []asset) []asset {
+	last := make(map[string]int, len(assets))
+	for i, a := range assets {
+		last[a.dest] = i
+	}
+
+	var unique []asset
+	for i, a := range assets {
+		if last[a.dest] == i {
+			unique = append(unique, a)
+		}
+	}
+	return unique
+}
+
+// Fingerprint and copy assets with inert flag behaviors
+// Returns the fingerprint manifest, for rewriting references to the assets in pages
+func copyAssets(assets []asset, destRoot string, flags InertFlags) (map[string]string, error) {
+	assets = uniqueAssets(assets)
+	manifest, err := fingerprintAssets(assets, destRoot, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, a := range assets {
+		if err := copyFileEx(a.src, a.dest, flags); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	errs = append(errs, writeManifest(filepath.Join(destRoot, manifestFile), manifest))
+	return manifest, errors.Join(errs...)
+}
@@ -0,0 +1,192 @@
+package generator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPatterns(t *testing.T) {
+	tests := []struct {
+		patterns Patterns
+		path     string
+		expected bool
+	}{
+		{Patterns{"*.png"}, "img/logo.png", true},
+		{Patterns{"*.png"}, "img/logo.svg", false},
+		{Patterns{"drafts"}, "drafts/notes.txt", true},
+		{Patterns{"img/*.png"}, "img/logo.png", true},
+		{Patterns{"img/*.png"}, "posts/img/logo.png", false},
+		{Patterns{"posts/img"}, "posts/img/logo.png", true},
+		{Patterns{"*.css", "*.js"}, "main.js", true},
+		{nil, "main.js", false},
+	}
+
+	for _, test := range tests {
+		if result := test.patterns.Match(test.path); result != test.expected {
+			t.Fatalf("%v match %s: expected %v, result %v", test.patterns, test.path, test.expected, result)
+		}
+	}
+
+	var patterns Patterns
+	if err := patterns.Set("[a-"); err == nil {
+		t.Fatal("Expected an error for an invalid pattern")
+	}
+}
+
+func TestGenerateDirectoryAssets(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"src/index.md":            "# Home",
+		"src/logo.png":            "png",
+		"src/notes.txt":           "notes",
+		"src/.hidden":             "hidden",
+		"src/posts/post.md":       "# Post",
+		"src/posts/img/photo.jpg": "jpg",
+		"src/posts/raw.psd":       "psd",
+		"static/style.css":        "static style",
+		"static/logo.png":         "static png",
+	})
+
+	// Path of a file in the site
+	in := func(name string) string {
+		return filepath.Join(root, name)
+	}
+
+	flags := InertFlags{Root: root, Recursive: true, Exclude: Patterns{"*.psd", "notes.txt"}}
+	if err := GenerateDirectory(in("src"), "", in("dest"), flags); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"logo.png":            "png",
+		"style.css":           "static style",
+		"posts/img/photo.jpg": "jpg",
+	}
+	for name, content := range expected {
+		result, err := ReadFileS(filepath.Join(root, "dest", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != content {
+			t.Fatalf("%s: expected %q, result %q", name, content, result)
+		}
+	}
+
+	for _, name := range []string{"notes.txt", ".hidden", "posts/raw.psd"} {
+		if _, err := os.Stat(filepath.Join(root, "dest", name)); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("%s: expected not to be copied", name)
+		}
+	}
+
+	t.Run("Include", func(t *testing.T) {
+		flags := InertFlags{Root: root, Recursive: true, Include: Patterns{"*.jpg"}}
+		if err := GenerateDirectory(in("src"), "", in("include"), flags); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := os.Stat(in("include/posts/img/photo.jpg")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(in("include/logo.png")); !errors.Is(err, os.ErrNotExist) {
+			t.Fatal("logo.png: expected not to be copied")
+		}
+	})
+
+	t.Run("NotRecursive", func(t *testing.T) {
+		if err := GenerateDirectory(in("src"), "", in("flat"), InertFlags{Root: root}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := os.Stat(in("flat/posts")); !errors.Is(err, os.ErrNotExist) {
+			t.Fatal("posts: expected not to be copied without -r")
+		}
+	})
+
+	t.Run("Minify", func(t *testing.T) {
+		writeTestFiles(t, root, map[string]string{
+			"src/main.js":    "// Comment\nlet a = 1;\n",
+			"static/min.css": "a {\n  color: red;\n}\n",
+		})
+
+		var types FileTypes
+		if err := types.Set("css, JS,html"); err != nil {
+			t.Fatal(err)
+		}
+		if err := types.Set("png"); err == nil {
+			t.Fatal("Expected an error for an unknown file type")
+		}
+
+		if err := GenerateDirectory(in("src"), "", in("minified"), InertFlags{Root: root, Minify: types}); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := map[string]string{"main.js": "let a=1;", "min.css": "a{color:red}", "logo.png": "png"}
+		for name, content := range expected {
+			if result, _ := ReadFileS(filepath.Join(root, "minified", name)); result != content {
+				t.Fatalf("%s: expected %q, result %q", name, content, result)
+			}
+		}
+
+		page, err := ReadFileS(in("minified/index.html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(page, "<h1 id=home>Home</h1>") || strings.Contains(page, "\n<") {
+			t.Fatalf("index.html: expected minified html, result:\n%s", page)
+		}
+	})
+
+	t.Run("NoClobber", func(t *testing.T) {
+		writeTestFiles(t, root, map[string]string{"src/logo.png": "new png"})
+		if err := GenerateDirectory(in("src"), "", in("dest"), InertFlags{Root: root, NoClobber: true}); err != nil {
+			t.Fatal(err)
+		}
+
+		if result, _ := ReadFileS(in("dest/logo.png")); result != "png" {
+			t.Fatalf("Expected existing file to be kept, result %q", result)
+		}
+	})
+}
+
+func TestGenerateSiteRoot(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"inert.toml":              "recursive = true",
+		"index.md":                "# Home",
+		"logo.png":                "png",
+		"posts/post.md":           "# Post",
+		"layouts/default.html":    defaultTemplate,
+		"static/style.css":        "static style",
+		"themes/plain/readme.txt": "theme",
+	})
+
+	flags := InertFlags{Root: root, Recursive: true, Layouts: filepath.Join(root, "layouts"), Fingerprint: Patterns{"*.css"}}
+	dest := filepath.Join(root, "public")
+
+	// Building again must not pick up the output of the first build
+	for i := 0; i < 2; i++ {
+		if err := GenerateDirectory(root, "", dest, flags); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var files []string
+	err := filepath.WalkDir(dest, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(dest, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "assets.json index.html logo.png posts/post.html style.8c6daaa7.css"
+	if strings.Join(files, " ") != expected {
+		t.Fatalf("Expected: %s\nResult: %s", expected, strings.Join(files, " "))
+	}
+}
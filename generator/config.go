@@ -0,0 +1,216 @@
+package generator
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/almushel/inertHTML/parser"
+)
+
+// Names of configuration files, in order of preference
+var configFiles = []string{"inert.toml", "inert.yaml", "inert.yml", "inert.json"}
+
+// Find and parse the first configuration file in dir
+// Returns the file name, or "" and empty metadata if there is none
+func LoadConfig(dir string) (parser.Metadata, string, error) {
+	for _, name := range configFiles {
+		file := filepath.Join(dir, name)
+		src, err := ReadFileS(file)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, file, err
+		}
+
+		var config parser.Metadata
+		switch filepath.Ext(name) {
+		case ".toml":
+			config, err = parser.ParseTOML(src)
+		case ".json":
+			config, err = parser.ParseJSON(src)
+		default:
+			config, err = parser.ParseYAML(src)
+		}
+
+		if syntaxErr, ok := err.(*parser.SyntaxError); ok {
+			syntaxErr.File = file
+		}
+		return config, file, err
+	}
+
+	return make(parser.Metadata), "", nil
+}
+
+// Settings in the site configuration file and the flags they stand for
+var configFlags = map[string]string{
+	"noClobber":    "n",
+	"interactive":  "i",
+	"recursive":    "r",
+	"verbose":      "v",
+	"pagesAsDirs":  "pagesAsDirs",
+	"strict":       "strict",
+	"drafts":       "drafts",
+	"future":       "future",
+	"expired":      "expired",
+	"tableClasses": "tableClasses",
+	"tableExt":     "tableExt",
+	"stripTitle":   "stripTitle",
+	"safe":         "safe",
+	"output":       "o",
+	"template":     "t",
+	"baseURL":      "baseURL",
+	"theme":        "theme",
+	"layouts":      "layouts",
+	"include":      "include",
+	"exclude":      "exclude",
+	"fingerprint":  "fingerprint",
+	"minify":       "minify",
+}
+
+// Settings in the site configuration file that are not flags
+var siteSettings = []string{"source", "title", "params", "menus", "sections"}
+
+// Set the flags in flagSet from the site configuration in file, except those given on the command line
+// Returns the configuration with the effective value of every flag setting defined in flagSet
+func ApplyConfig(flagSet *flag.FlagSet, config parser.Metadata, file string) (parser.Metadata, error) {
+	setFlags := make(map[string]bool)
+	flagSet.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
+	var keys []string
+	for key := range config {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		name, ok := configFlags[key]
+		if !ok {
+			if !slices.Contains(siteSettings, key) {
+				return nil, fmt.Errorf("%s: unknown setting %q", file, key)
+			}
+			continue
+		}
+
+		if setFlags[name] {
+			continue
+		}
+
+		// Lists set repeatable flags once per item
+		values, ok := config[key].([]any)
+		if !ok {
+			values = []any{config[key]}
+		}
+		for _, value := range values {
+			if err := flagSet.Set(name, fmt.Sprint(value)); err != nil {
+				return nil, fmt.Errorf("%s: %s: %s", file, key, err)
+			}
+		}
+	}
+
+	effective := make(parser.Metadata, len(config)+len(configFlags))
+	for key, value := range config {
+		effective[key] = value
+	}
+	for key, name := range configFlags {
+		f := flagSet.Lookup(name)
+		if f == nil {
+			continue
+		}
+
+		if getter, ok := f.Value.(flag.Getter); ok {
+			effective[key] = getter.Get()
+		} else {
+			effective[key] = f.Value.String()
+		}
+	}
+
+	return effective, nil
+}
+
+// The site configuration in flags, over the configuration of its theme
+func SiteConfig(flags InertFlags) (parser.Metadata, error) {
+	config := make(parser.Metadata)
+	if flags.Theme != "" {
+		var err error
+		config, _, err = LoadConfig(themeDir(flags))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return mergeConfig(config, flags.Site), nil
+}
+
+// Copy of base with the values of override added, merging nested maps
+func mergeConfig(base, override map[string]any) map[string]any {
+	result := make(map[string]any, len(base)+len(override))
+	for key, value := range base {
+		result[key] = value
+	}
+
+	for key, value := range override {
+		baseMap, baseOk := result[key].(map[string]any)
+		overrideMap, overrideOk := value.(map[string]any)
+		if baseOk && overrideOk {
+			result[key] = mergeConfig(baseMap, overrideMap)
+		} else {
+			result[key] = value
+		}
+	}
+
+	return result
+}
+
+// Page metadata with the defaults for its section from the site configuration filled in
+// e.g. [sections.blog] layout = "post" in inert.toml
+func withSectionDefaults(metadata parser.Metadata, section string, config parser.Metadata) parser.Metadata {
+	sections, _ := config["sections"].(map[string]any)
+	defaults, ok := sections[section].(map[string]any)
+	if section == "" || !ok {
+		return metadata
+	}
+	return mergeConfig(defaults, metadata)
+}
+
+// A link in a menu from the site configuration
+type MenuEntry struct {
+	Name   string
+	URL    string
+	Weight int
+}
+
+// Menus by name from the site configuration, each sorted by weight
+// e.g. [[menus.main]] name = "Blog" url = "/blog/" weight = 1 in inert.toml
+func configMenus(config parser.Metadata) map[string][]MenuEntry {
+	menus := make(map[string][]MenuEntry)
+
+	configured, _ := config["menus"].(map[string]any)
+	for name, items := range configured {
+		list, _ := items.([]any)
+		for _, item := range list {
+			values, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			weight, _ := values["weight"].(int)
+			menus[name] = append(menus[name], MenuEntry{
+				Name:   parser.Metadata(values).GetString("name"),
+				URL:    parser.Metadata(values).GetString("url"),
+				Weight: weight,
+			})
+		}
+
+		slices.SortStableFunc(menus[name], func(a, b MenuEntry) int {
+			return a.Weight - b.Weight
+		})
+	}
+
+	return menus
+}
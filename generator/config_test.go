@@ -0,0 +1,135 @@
+package generator
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/almushel/inertHTML/parser"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		files    map[string]string
+		expected string
+	}{
+		{map[string]string{}, "map[]"},
+		{map[string]string{"inert.toml": "title = \"TOML\"\n[params]\na = 1"}, "map[params:map[a:1] title:TOML]"},
+		{map[string]string{"inert.yaml": "title: YAML"}, "map[title:YAML]"},
+		{map[string]string{"inert.yml": "title: YML"}, "map[title:YML]"},
+		{map[string]string{"inert.json": `{"title": "JSON"}`}, "map[title:JSON]"},
+		{map[string]string{"inert.json": `{"title": "JSON"}`, "inert.toml": `title = "TOML"`}, "map[title:TOML]"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.files), func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestFiles(t, dir, test.files)
+
+			config, _, err := LoadConfig(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(config) != test.expected {
+				t.Fatalf("Expected: %s\nResult: %v", test.expected, config)
+			}
+		})
+	}
+
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"inert.toml": "title = \"TOML\"\ntitle = \"again\""})
+	_, file, err := LoadConfig(dir)
+	if expected := filepath.Join(dir, "inert.toml") + ":2: "; err == nil || !strings.HasPrefix(err.Error(), expected) || file != filepath.Join(dir, "inert.toml") {
+		t.Fatalf("Expected an error starting with %q, got %v", expected, err)
+	}
+}
+
+func TestSiteConfig(t *testing.T) {
+	theme := map[string]any{
+		"title":  "Theme",
+		"params": map[string]any{"color": "blue", "author": "Theme"},
+	}
+	site := parser.Metadata{
+		"params": map[string]any{"author": "Site"},
+		"menus": map[string]any{"main": []any{
+			map[string]any{"name": "Blog", "url": "/blog/", "weight": 2},
+			map[string]any{"name": "Home", "url": "/", "weight": 1},
+			map[string]any{"name": "About", "url": "/about/"},
+		}},
+		"sections": map[string]any{"blog": map[string]any{"layout": "post", "draft": true}},
+	}
+
+	config := parser.Metadata(mergeConfig(theme, site))
+	if expected := "map[author:Site color:blue]"; fmt.Sprint(config["params"]) != expected || config.GetString("title") != "Theme" {
+		t.Fatalf("Expected site values over theme values, got %v", config)
+	}
+
+	menus := configMenus(config)
+	if expected := "[{About /about/ 0} {Home / 1} {Blog /blog/ 2}]"; fmt.Sprint(menus["main"]) != expected {
+		t.Fatalf("Expected: %s\nResult: %v", expected, menus["main"])
+	}
+
+	page := withSectionDefaults(parser.Metadata{"draft": false}, "blog", config)
+	if page.GetString("layout") != "post" || page.GetBool("draft") {
+		t.Fatalf("Expected section defaults under page values, got %v", page)
+	}
+	if page := withSectionDefaults(parser.Metadata{}, "docs", config); len(page) != 0 {
+		t.Fatalf("Expected no defaults for other sections, got %v", page)
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	tests := []struct {
+		args     []string
+		config   parser.Metadata
+		expected string
+		err      string
+	}{
+		{nil, parser.Metadata{}, "map[baseURL: exclude:[] minify:[] recursive:false verbose:false]", ""},
+		{nil, parser.Metadata{"verbose": true, "baseURL": "https://example.com/", "title": "Site"},
+			"map[baseURL:https://example.com/ exclude:[] minify:[] recursive:false title:Site verbose:true]", ""},
+		{[]string{"-v=false", "-baseURL", "/flag/"}, parser.Metadata{"verbose": true, "baseURL": "/config/", "recursive": true},
+			"map[baseURL:/flag/ exclude:[] minify:[] recursive:true verbose:false]", ""},
+		{nil, parser.Metadata{"exclude": []any{"*.tmp", "drafts/*"}, "minify": []any{"css", "js"}},
+			"map[baseURL: exclude:[*.tmp drafts/*] minify:[css js] recursive:false verbose:false]", ""},
+		{[]string{"-exclude", "*.bak"}, parser.Metadata{"exclude": []any{"*.tmp"}, "minify": "html"},
+			"map[baseURL: exclude:[*.bak] minify:[html] recursive:false verbose:false]", ""},
+		{nil, parser.Metadata{"verbos": true}, "", `inert.toml: unknown setting "verbos"`},
+		{nil, parser.Metadata{"minify": "png"}, "", "inert.toml: minify: "},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.args, test.config), func(t *testing.T) {
+			var flags InertFlags
+			flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+			flagSet.BoolVar(&flags.Recursive, "r", false, "")
+			flagSet.BoolVar(&flags.Verbose, "v", false, "")
+			flagSet.StringVar(&flags.BaseURL, "baseURL", "", "")
+			flagSet.Var(&flags.Exclude, "exclude", "")
+			flagSet.Var(&flags.Minify, "minify", "")
+			if err := flagSet.Parse(test.args); err != nil {
+				t.Fatal(err)
+			}
+
+			effective, err := ApplyConfig(flagSet, test.config, "inert.toml")
+			if test.err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+					t.Fatalf("Expected an error starting with %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if fmt.Sprint(effective) != test.expected {
+				t.Fatalf("Expected: %s\nResult: %v", test.expected, effective)
+			}
+			if fmt.Sprint(effective["verbose"]) != fmt.Sprint(flags.Verbose) || effective["baseURL"] != flags.BaseURL {
+				t.Fatalf("Effective settings %v do not match flags %+v", effective, flags)
+			}
+		})
+	}
+}
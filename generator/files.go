@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -22,45 +23,37 @@ func ReadFileS(name string) (string, error) {
 }
 
 func FileCopy(src, dest string) error {
-	var err error
-
 	srcFile, err := os.Open(src)
-	defer srcFile.Close()
 	if err != nil {
 		return err
 	}
+	defer srcFile.Close()
 
 	destFile, err := CreateAll(dest)
-	defer destFile.Close()
 	if err != nil {
 		return err
 	}
+	defer destFile.Close()
 
-	buf := make([]byte, 2048)
-	for bytesRead, _ := srcFile.Read(buf); bytesRead != 0; bytesRead, _ = srcFile.Read(buf) {
-		destFile.Write(buf)
+	if _, err = io.Copy(destFile, srcFile); err != nil {
+		return err
 	}
 
-	return err
+	return destFile.Close()
 }
 
+// Copy every file in the src directory tree to the same path under dest
 func FileCopyRecursive(src, dest string) error {
-	wd, err := os.Getwd()
-	if err != nil {
-		return err
-	}
-
-	err = fs.WalkDir(os.DirFS(wd), src,
-		func(path string, d fs.DirEntry, err error) error {
-			if d == nil || d.IsDir() {
-				return err
-			}
-
-			var destPath string = dest + path[len(src):]
-
-			return FileCopy(path, destPath)
-		},
-	)
-
-	return err
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		return FileCopy(path, filepath.Join(dest, rel))
+	})
 }
@@ -0,0 +1,209 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/almushel/inertHTML/parser"
+)
+
+// File in the output directory mapping asset paths to their fingerprinted paths
+const manifestFile = "assets.json"
+
+// Number of hex digits of the content hash added to fingerprinted file names
+const fingerprintLength = 8
+
+// Attributes of a tag, after its name, in order so that quoted values are never searched for attributes
+var tagAttributePattern = regexp.MustCompile(`\s+([^\s"'>/=]+)(?:\s*=\s*("[^"]*"|'[^']*'|[^\s"'=<>` + "`" + `]+))?`)
+
+// Give assets matching the fingerprint patterns a name containing a hash of their content
+// e.g. css/style.css -> css/style.3f9a1c2b.css
+// Returns the manifest of renamed files, relative to destRoot with slashes
+func fingerprintAssets(assets []asset, destRoot string, flags InertFlags) (map[string]string, error) {
+	if len(flags.Fingerprint) == 0 {
+		return nil, nil
+	}
+
+	manifest := make(map[string]string)
+
+	for i, a := range assets {
+		rel, err := filepath.Rel(destRoot, a.dest)
+		if err != nil {
+			return nil, err
+		}
+		rel = filepath.ToSlash(rel)
+		if !flags.Fingerprint.Match(rel) {
+			continue
+		}
+
+		hash, err := assetHash(a.src, flags)
+		if err != nil {
+			return nil, err
+		}
+
+		ext := path.Ext(rel)
+		fingerprinted := rel[:len(rel)-len(ext)] + "." + hash[:fingerprintLength] + ext
+		manifest[rel] = fingerprinted
+		assets[i].dest = filepath.Join(destRoot, filepath.FromSlash(fingerprinted))
+	}
+
+	return manifest, nil
+}
+
+// Hash of the content the asset is published with, after minifying it if flags say to
+func assetHash(file string, flags InertFlags) (string, error) {
+	minifier := flags.Minify.minifier(file)
+	if minifier == nil {
+		return fileHash(file)
+	}
+
+	src, err := ReadFileS(file)
+	if err != nil {
+		return "", err
+	}
+	result, err := minifier(src)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", file, err)
+	}
+
+	hash := sha256.Sum256([]byte(result))
+	return hex.EncodeToString(hash[:]), nil
+}
+
+func fileHash(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Written on every build that fingerprints files, regardless of the overwrite flags, so it always matches the output
+func writeManifest(file string, manifest map[string]string) error {
+	if len(manifest) == 0 {
+		return nil
+	}
+
+	src, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writePage(file, string(src)+"\n")
+}
+
+// Point href and src attributes in rendered html at fingerprinted assets
+// pageURL: Path of the page from the root of the site, which relative references are resolved against
+func rewriteAssetURLs(src, pageURL, baseURL string, manifest map[string]string) string {
+	var result strings.Builder
+	last := 0
+
+	for _, token := range parser.TokenizeHTML(src) {
+		if token.Type != parser.HTMLStartTag && token.Type != parser.HTMLSelfClosingTag {
+			continue
+		}
+
+		start := token.Offset + 1 + len(token.Data)
+		end := token.Offset + len(token.Raw)
+		for _, match := range tagAttributePattern.FindAllStringSubmatchIndex(src[start:end], -1) {
+			if match[4] < 0 {
+				continue
+			}
+			name := strings.ToLower(src[start+match[2] : start+match[3]])
+			if name != "href" && name != "src" {
+				continue
+			}
+
+			valueStart, valueEnd := start+match[4], start+match[5]
+			value := src[valueStart:valueEnd]
+			if value[0] == '"' || value[0] == '\'' {
+				value = value[1 : len(value)-1]
+			}
+
+			ref, ok := resolveAsset(html.UnescapeString(value), pageURL, baseURL, manifest)
+			if !ok {
+				continue
+			}
+
+			result.WriteString(src[last:valueStart])
+			result.WriteString(`"` + html.EscapeString(ref) + `"`)
+			last = valueEnd
+		}
+	}
+
+	if last == 0 {
+		return src
+	}
+	result.WriteString(src[last:])
+	return result.String()
+}
+
+// The fingerprinted form of a reference to an asset, keeping its directory, query and fragment
+// References can be relative to pageURL, start with the path of baseURL or be full urls under baseURL
+func resolveAsset(ref, pageURL, baseURL string, manifest map[string]string) (string, bool) {
+	refPath, suffix := ref, ""
+	if i := strings.IndexAny(ref, "?#"); i >= 0 {
+		refPath, suffix = ref[:i], ref[i:]
+	}
+	if refPath == "" || strings.HasSuffix(refPath, "/") {
+		return "", false
+	}
+
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return "", false
+	}
+	basePath := "/" + strings.Trim(base.Path, "/") + "/"
+	basePath = strings.Replace(basePath, "//", "/", 1)
+
+	var key string
+	switch {
+	case isAbsoluteURL(refPath):
+		prefix := strings.TrimSuffix(baseURL, "/") + "/"
+		if base.Host == "" || !strings.HasPrefix(refPath, prefix) {
+			return "", false
+		}
+		key = refPath[len(prefix):]
+	case strings.HasPrefix(refPath, "/"):
+		if !strings.HasPrefix(refPath, basePath) {
+			return "", false
+		}
+		key = refPath[len(basePath):]
+	default:
+		dir := pageURL
+		if !strings.HasSuffix(dir, "/") {
+			dir = path.Dir(dir)
+		}
+		key = strings.TrimPrefix(path.Join(dir, refPath), "/")
+	}
+
+	fingerprinted, ok := manifest[path.Clean(key)]
+	if !ok {
+		return "", false
+	}
+	return refPath[:strings.LastIndexByte(refPath, '/')+1] + path.Base(fingerprinted) + suffix, true
+}
+
+// Fingerprinted path of an asset, given by its path from the root of the site
+// Paths of files that are not fingerprinted are returned unchanged
+func fingerprint(manifest map[string]string, file string) string {
+	if ref, ok := resolveAsset(file, "/", "", manifest); ok {
+		return ref
+	}
+	return file
+}
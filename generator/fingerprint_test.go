@@ -0,0 +1,165 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveAsset(t *testing.T) {
+	manifest := map[string]string{
+		"style.css":           "style.12345678.css",
+		"posts/img/photo.jpg": "posts/img/photo.abcdef01.jpg",
+	}
+
+	tests := []struct {
+		ref, pageURL, baseURL, expected string
+	}{
+		{"style.css", "/index.html", "", "style.12345678.css"},
+		{"/style.css", "/posts/post.html", "", "/style.12345678.css"},
+		{"../style.css?v=1#top", "/posts/post.html", "", "../style.12345678.css?v=1#top"},
+		{"img/photo.jpg", "/posts/", "", "img/photo.abcdef01.jpg"},
+		{"img/photo.jpg", "/posts/post.html", "", "img/photo.abcdef01.jpg"},
+		{"/blog/style.css", "/", "https://example.com/blog/", "/blog/style.12345678.css"},
+		{"https://example.com/blog/style.css", "/", "https://example.com/blog/", "https://example.com/blog/style.12345678.css"},
+		{"/style.css", "/", "https://example.com/blog/", ""},
+		{"https://other.com/style.css", "/", "", ""},
+		{"script.js", "/", "", ""},
+		{"#style.css", "/", "", ""},
+	}
+
+	for _, test := range tests {
+		result, ok := resolveAsset(test.ref, test.pageURL, test.baseURL, manifest)
+		if ok != (test.expected != "") || result != test.expected {
+			t.Fatalf("%s from %s: expected %q, result %q", test.ref, test.pageURL, test.expected, result)
+		}
+	}
+}
+
+func TestRewriteAssetURLs(t *testing.T) {
+	manifest := map[string]string{"style.css": "style.12345678.css", "a&b.png": "a&b.abcdef01.png"}
+
+	tests := []struct {
+		name, src, expected string
+	}{
+		{"Href", `<link rel="stylesheet" href="style.css">`, `<link rel="stylesheet" href="style.12345678.css">`},
+		{"UnquotedSrc", `<img src=a&amp;b.png alt="">`, `<img src="a&amp;b.abcdef01.png" alt="">`},
+		{"SingleQuotes", `<link href='/style.css'/>`, `<link href="/style.12345678.css"/>`},
+		{"QuotedAttribute", `<a title="x href=style.css" href="other.css">`, `<a title="x href=style.css" href="other.css">`},
+		{"Text", `<p>href="style.css"</p><code>&lt;a href="style.css"&gt;</code>`, `<p>href="style.css"</p><code>&lt;a href="style.css"&gt;</code>`},
+		{"Script", `<script>let a = '<img src="style.css">'</script>`, `<script>let a = '<img src="style.css">'</script>`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := rewriteAssetURLs(test.src, "/", "", manifest)
+			if result != test.expected {
+				t.Fatalf("\nExpected:\n%s\nResult:\n%s", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestGenerateDirectoryFingerprint(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"src/index.md":      "# Home\n\n![Photo](img/photo.jpg)",
+		"src/posts/post.md": "# Post\n\n[Home](../index.html) ![Photo](../img/photo.jpg)",
+		"src/img/photo.jpg": "jpg",
+		"static/style.css":  "body {}",
+		"static/robots.txt": "robots",
+		"layouts/default.html": "<!DOCTYPE html>\n<html>\n<head><title>{{ Title }}</title>" +
+			`<link rel="stylesheet" href="{{ relURL "style.css" }}"></head>` +
+			"\n<body>{{ Content }}<script>const css = {{ fingerprint \"style.css\" }}</script></body>\n</html>",
+	})
+
+	dest := filepath.Join(root, "dest")
+	flags := InertFlags{Root: root, Recursive: true, Layouts: filepath.Join(root, "layouts"), Fingerprint: Patterns{"*.css", "*.jpg"}}
+	if err := GenerateDirectory(filepath.Join(root, "src"), "", dest, flags); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := readManifest(filepath.Join(dest, manifestFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	css, jpg := manifest["style.css"], manifest["img/photo.jpg"]
+	if !strings.HasPrefix(css, "style.") || len(css) != len("style..css")+fingerprintLength {
+		t.Fatalf("Unexpected fingerprinted name %q", css)
+	}
+	if _, ok := manifest["robots.txt"]; ok {
+		t.Fatal("robots.txt: expected not to be fingerprinted")
+	}
+
+	for _, name := range []string{css, jpg, "robots.txt"} {
+		if _, err := os.Stat(filepath.Join(dest, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"style.css", "img/photo.jpg"} {
+		if _, err := os.Stat(filepath.Join(dest, name)); err == nil {
+			t.Fatalf("%s: expected to be copied only with a fingerprint", name)
+		}
+	}
+
+	expected := map[string][]string{
+		"index.html": {`href="/` + css + `"`, `src="` + jpg + `"`, `const css = "` + css + `"`},
+		"posts/post.html": {
+			`href="/` + css + `"`,
+			`src="../` + jpg + `"`,
+			`href="../index.html"`,
+		},
+	}
+	for name, contents := range expected {
+		result, err := ReadFileS(filepath.Join(dest, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, content := range contents {
+			if !strings.Contains(result, content) {
+				t.Fatalf("%s:\nExpected to contain:\n%s\nResult:\n%s", name, content, result)
+			}
+		}
+	}
+
+	// Files that are no longer published are left out of the manifest of the next build
+	if err := os.Remove(filepath.Join(root, "src", "img", "photo.jpg")); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateDirectory(filepath.Join(root, "src"), "", dest, flags); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err = readManifest(filepath.Join(dest, manifestFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := manifest["img/photo.jpg"]; ok || manifest["style.css"] != css {
+		t.Fatalf("Expected only style.css in the manifest, got %v", manifest)
+	}
+
+	// Minified files are named after the hash of the content that is written
+	flags.Minify = FileTypes{"css"}
+	if err := GenerateDirectory(filepath.Join(root, "src"), "", filepath.Join(root, "minified"), flags); err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256([]byte("body{}"))
+	css = "style." + hex.EncodeToString(hash[:])[:fingerprintLength] + ".css"
+	if result, err := ReadFileS(filepath.Join(root, "minified", css)); err != nil || result != "body{}" {
+		t.Fatalf("%s: expected the minified stylesheet, got %q (%v)", css, result, err)
+	}
+}
+
+func readManifest(file string) (map[string]string, error) {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest map[string]string
+	err = json.Unmarshal(src, &manifest)
+	return manifest, err
+}
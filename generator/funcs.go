@@ -0,0 +1,284 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/almushel/inertHTML/parser"
+)
+
+// Functions available to templates
+// dirs: Directories that partials are loaded from, none if partials are not available
+func (cache *templateCache) funcs(dirs []string) template.FuncMap {
+	return template.FuncMap{
+		"partial": func(name string, data any) (template.HTML, error) {
+			return cache.partial(dirs, name, data)
+		},
+		"relURL": func(path string) string {
+			return relURL(cache.flags.BaseURL, path)
+		},
+		"absURL": func(path string) string {
+			return absURL(cache.flags.BaseURL, path)
+		},
+		"readFile": func(path string) (string, error) {
+			return readFile(cache.flags.Root, path)
+		},
+		"markdownify": func(md string) (template.HTML, error) {
+			return markdownify(md, cache.flags)
+		},
+		"fingerprint": func(path string) string {
+			return fingerprint(cache.manifest, path)
+		},
+
+		"dateFormat": dateFormat,
+		"truncate":   truncate,
+		"slugify":    slugify,
+		"where":      where,
+		"sort":       sortPages,
+		"first":      first,
+		"jsonify":    jsonify,
+
+		// Mark trusted strings as safe to insert without escaping
+		"safeHTML":     func(s string) template.HTML { return template.HTML(s) },
+		"safeHTMLAttr": func(s string) template.HTMLAttr { return template.HTMLAttr(s) },
+		"safeCSS":      func(s string) template.CSS { return template.CSS(s) },
+		"safeJS":       func(s string) template.JS { return template.JS(s) },
+		"safeURL":      func(s string) template.URL { return template.URL(s) },
+	}
+}
+
+// Format a time, or a string containing a date, with a Go time layout
+// Missing and zero times are formatted as ""
+func dateFormat(layout string, value any) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case time.Time:
+		if value.IsZero() {
+			return "", nil
+		}
+		return value.Format(layout), nil
+	case string:
+		if t, ok := (parser.Metadata{"": value}).GetTime(""); ok {
+			return t.Format(layout), nil
+		}
+	}
+	return "", fmt.Errorf("dateFormat: %v is not a date", value)
+}
+
+// True for urls with a scheme or host, which are left unchanged by relURL and absURL
+func isAbsoluteURL(path string) bool {
+	u, err := url.Parse(path)
+	return err == nil && (u.Scheme != "" || u.Host != "")
+}
+
+// Path from the root of the site, under the path of baseURL
+// e.g. css/main.css -> /blog/css/main.css with a baseURL of https://example.com/blog/
+func relURL(baseURL, path string) string {
+	if isAbsoluteURL(path) {
+		return path
+	}
+
+	basePath := "/"
+	if u, err := url.Parse(baseURL); err == nil && u.Path != "" {
+		basePath = u.Path
+	}
+	return strings.TrimSuffix(basePath, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+// Full url under baseURL, or the same as relURL if there is no baseURL
+func absURL(baseURL, path string) string {
+	if isAbsoluteURL(path) {
+		return path
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return relURL(baseURL, path)
+	}
+	return u.Scheme + "://" + u.Host + relURL(baseURL, path)
+}
+
+// Render markdown to html, without the paragraph around single paragraphs
+func markdownify(md string, flags InertFlags) (template.HTML, error) {
+	result, err := parser.MDtoHTMLEx(md, parser.InertParserOptions{
+		Sanitize:        flags.Sanitize,
+		TableClasses:    flags.TableClasses,
+		TableExtensions: flags.TableExtensions,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	body := result.Body
+	if strings.HasPrefix(body, "<p>") && strings.HasSuffix(body, "</p>") && strings.Count(body, "<p>") == 1 {
+		body = body[len("<p>") : len(body)-len("</p>")]
+	}
+	return template.HTML(body), nil
+}
+
+// Shorten text to at most length bytes at a word boundary, ending with an ellipsis
+func truncate(length int, text string) (string, error) {
+	if length <= len("…") {
+		return "", fmt.Errorf("truncate: length must be greater than %d, got %d", len("…"), length)
+	}
+	return parser.TruncateText(text, length), nil
+}
+
+// Lowercase letters and digits, with every other run of characters replaced by a single -
+// e.g. "Go & HTML: A Guide" -> go-html-a-guide
+func slugify(text string) string {
+	var slug strings.Builder
+
+	separate := false
+	for _, r := range strings.ToLower(text) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			separate = true
+			continue
+		}
+
+		if separate && slug.Len() > 0 {
+			slug.WriteByte('-')
+		}
+		separate = false
+		slug.WriteRune(r)
+	}
+
+	return slug.String()
+}
+
+// Value of a Page field by name, or of a frontmatter value for keys like Params.author
+func pageField(page *Page, key string) (any, error) {
+	if name, ok := strings.CutPrefix(key, "Params."); ok {
+		return page.Params[name], nil
+	}
+
+	field := reflect.ValueOf(page).Elem().FieldByName(key)
+	if !field.IsValid() || !field.CanInterface() {
+		return nil, fmt.Errorf("unknown page field %q", key)
+	}
+	return field.Interface(), nil
+}
+
+// Pages whose key field equals value, or contains it for lists such as Params.tags
+// Values are compared by their formatted text, so 2024 matches "2024"
+func where(pages []*Page, key string, value any) ([]*Page, error) {
+	var result []*Page
+	for _, page := range pages {
+		field, err := pageField(page, key)
+		if err != nil {
+			return nil, fmt.Errorf("where: %w", err)
+		}
+
+		matches := fmt.Sprint(field) == fmt.Sprint(value)
+		if list := reflect.ValueOf(field); list.Kind() == reflect.Slice {
+			for i := 0; i < list.Len() && !matches; i++ {
+				matches = fmt.Sprint(list.Index(i).Interface()) == fmt.Sprint(value)
+			}
+		}
+
+		if matches {
+			result = append(result, page)
+		}
+	}
+	return result, nil
+}
+
+// Copy of pages sorted by key field, in "asc" (default) or "desc" order
+// Pages with equal values keep their order
+func sortPages(pages []*Page, key string, order ...string) ([]*Page, error) {
+	descending := false
+	if len(order) > 1 {
+		return nil, errors.New("sort: expected at most one order")
+	} else if len(order) == 1 {
+		switch order[0] {
+		case "asc":
+		case "desc":
+			descending = true
+		default:
+			return nil, fmt.Errorf("sort: order must be asc or desc, got %q", order[0])
+		}
+	}
+
+	values := make(map[*Page]any, len(pages))
+	for _, page := range pages {
+		field, err := pageField(page, key)
+		if err != nil {
+			return nil, fmt.Errorf("sort: %w", err)
+		}
+		values[page] = field
+	}
+
+	result := slices.Clone(pages)
+	slices.SortStableFunc(result, func(a, b *Page) int {
+		if descending {
+			return compareValues(values[b], values[a])
+		}
+		return compareValues(values[a], values[b])
+	})
+	return result, nil
+}
+
+// Compare times and numbers by value and anything else by its formatted text
+func compareValues(a, b any) int {
+	if a, ok := a.(time.Time); ok {
+		if b, ok := b.(time.Time); ok {
+			return a.Compare(b)
+		}
+	}
+
+	if a, ok := toFloat(a); ok {
+		if b, ok := toFloat(b); ok {
+			switch {
+			case a < b:
+				return -1
+			case a > b:
+				return 1
+			}
+			return 0
+		}
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func toFloat(value any) (float64, bool) {
+	switch value := value.(type) {
+	case int:
+		return float64(value), true
+	case float64:
+		return value, true
+	}
+	return 0, false
+}
+
+// The first n pages, or all of them if there are fewer
+func first(n int, pages []*Page) ([]*Page, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("first: n must not be negative, got %d", n)
+	}
+	return pages[:min(n, len(pages))], nil
+}
+
+// Contents of a file inside the site root
+func readFile(root, path string) (string, error) {
+	if !filepath.IsLocal(path) {
+		return "", fmt.Errorf("readFile: %q is not inside the site root", path)
+	}
+	return ReadFileS(filepath.Join(root, path))
+}
+
+// Encode value as JSON, safe to use inside <script> tags
+func jsonify(value any) (template.JS, error) {
+	data, err := json.Marshal(value)
+	return template.JS(data), err
+}
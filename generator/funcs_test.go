@@ -0,0 +1,106 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/almushel/inertHTML/parser"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	pages := []*Page{
+		{Title: "Alpha", Section: "blog", Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			Params: parser.Metadata{"tags": []any{"go", "web"}, "weight": 2, "year": 2024}},
+		{Title: "Beta", Section: "docs", Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			Params: parser.Metadata{"tags": []any{"web"}, "weight": 10}},
+		{Title: "Gamma", Section: "blog", Date: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			Params: parser.Metadata{"weight": 1, "summary": "Some *markdown* & text"}},
+	}
+	site := newSite(pages)
+
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"data/greeting.txt": "hello"})
+
+	tests := []struct {
+		name, template, expected string
+	}{
+		{"dateFormat", `{{ .Page.Date | dateFormat "Jan 2, 2006" }}`, "Mar 1, 2024"},
+		{"dateFormat string", `{{ dateFormat "2006" "2023-05-06" }}`, "2023"},
+		{"dateFormat zero", `{{ .Page.PublishDate | dateFormat "2006" }}`, ""},
+		{"dateFormat missing", `{{ .Page.Params.missing | dateFormat "2006" }}`, ""},
+		{"relURL", `{{ relURL "css/main.css" }} {{ relURL "/img/a.png" }}`, "/blog/css/main.css /blog/img/a.png"},
+		{"relURL absolute", `{{ relURL "https://example.org/a" }}`, "https://example.org/a"},
+		{"absURL", `{{ absURL "css/main.css" }}`, "https://example.com/blog/css/main.css"},
+		{"markdownify", `{{ markdownify "Some *markdown* & text" }}`, "Some <em>markdown</em> &amp; text"},
+		{"markdownify paragraphs", `{{ markdownify "One\n\nTwo" }}`, "<p>One</p><p>Two</p>"},
+		{"markdownify table", `{{ markdownify "Table: T\n| a |\n| :-: |\n| b |" }}`,
+			`<div class="table-wrapper"><table><caption>T</caption><thead><tr><th class="align-center">a</th></tr></thead>` +
+				`<tbody><tr><td class="align-center">b</td></tr></tbody></table></div>`},
+		{"truncate", `{{ truncate 12 "The quick brown fox" }}`, "The quick…"},
+		{"slugify", `{{ slugify "Go & HTML: A Guide!" }}`, "go-html-a-guide"},
+		{"where", `{{ range where .Site.Pages "Section" "blog" }}{{ .Title }},{{ end }}`, "Alpha,Gamma,"},
+		{"where list", `{{ range where .Site.Pages "Params.tags" "web" }}{{ .Title }},{{ end }}`, "Alpha,Beta,"},
+		{"where number", `{{ range where .Site.Pages "Params.year" "2024" }}{{ .Title }},{{ end }}`, "Alpha,"},
+		{"sort", `{{ range sort .Site.Pages "Params.weight" }}{{ .Title }},{{ end }}`, "Gamma,Alpha,Beta,"},
+		{"sort desc", `{{ range sort .Site.Pages "Date" "desc" }}{{ .Title }},{{ end }}`, "Alpha,Gamma,Beta,"},
+		{"sort title", `{{ range sort .Site.Pages "Title" "desc" }}{{ .Title }},{{ end }}`, "Gamma,Beta,Alpha,"},
+		{"first", `{{ range first 2 (sort .Site.Pages "Title") }}{{ .Title }},{{ end }}`, "Alpha,Beta,"},
+		{"first more than pages", `{{ len (first 10 .Site.Pages) }}`, "3"},
+		{"readFile", `{{ readFile "data/greeting.txt" }}`, "hello"},
+		{"jsonify", `<script>var tags = {{ jsonify .Page.Params.tags }};</script>`, `<script>var tags = ["go","web"];</script>`},
+		{"safeHTML", `{{ "<b>bold</b>" }} {{ safeHTML "<b>bold</b>" }}`, "&lt;b&gt;bold&lt;/b&gt; <b>bold</b>"},
+		{"safeHTMLAttr", `<a {{ safeHTMLAttr "rel=\"me\"" }}>`, `<a rel="me">`},
+		{"safeCSS", `<p style="{{ safeCSS "color: red" }}">`, `<p style="color: red">`},
+		{"safeJS", `<script>{{ safeJS "var a = 1" }}</script>`, `<script>var a = 1</script>`},
+		{"safeURL", `<a href="{{ safeURL "tel:123" }}">`, `<a href="tel:123">`},
+	}
+
+	flags := InertFlags{BaseURL: "https://example.com/blog/", Root: dir, TableClasses: true, TableExtensions: true}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cache := newTemplateCache(flags)
+			tmpl, err := cache.parse(test.name, nil, test.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := executeTemplate(tmpl, pages[0], site)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != test.expected {
+				t.Fatalf("Expected:\n%s\nResult:\n%s", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestTemplateFuncErrors(t *testing.T) {
+	tests := map[string]string{
+		`{{ dateFormat "2006" "not a date" }}`:   "not a date",
+		`{{ truncate 2 "text" }}`:                "length must be greater than",
+		`{{ where .Site.Pages "Missing" "x" }}`:  "unknown page field",
+		`{{ sort .Site.Pages "Title" "up" }}`:    "order must be asc or desc",
+		`{{ first -1 .Site.Pages }}`:             "must not be negative",
+		`{{ readFile "../secret.txt" }}`:         "not inside the site root",
+		`{{ partial "header.html" . }}`:          "only available in template files",
+		`{{ where .Site.Pages "template" "x" }}`: "unknown page field",
+	}
+
+	page := &Page{Title: "Page", Params: make(parser.Metadata)}
+	site := newSite([]*Page{page})
+	for template, expected := range tests {
+		t.Run(template, func(t *testing.T) {
+			tmpl, err := newTemplateCache(InertFlags{}).parse("test", nil, template)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = executeTemplate(tmpl, page, site)
+			if err == nil || !strings.Contains(err.Error(), expected) {
+				t.Fatalf("Expected error containing %q, got %v", expected, err)
+			}
+		})
+	}
+}
@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
+	"time"
 
 	"github.com/almushel/inertHTML/parser"
 )
@@ -16,6 +16,29 @@ type InertFlags struct {
 	Recursive   bool // Recersively process subdirectories
 	Verbose     bool // Print steps to stdout
 	PagesAsDirs bool // Output individual files to "filename/index.html", except for files named "index.md"
+	Strict      bool // Treat parser warnings as errors
+
+	Drafts  bool // Include pages with draft: true in directory builds
+	Future  bool // Include pages whose date or publishDate is in the future in directory builds
+	Expired bool // Include pages whose expiryDate has passed in directory builds
+
+	Sanitize        parser.SanitizePolicy // Policy for raw html and link urls in markdown
+	TableClasses    bool                  // Align table cells with classes instead of inline styles
+	TableExtensions bool                  // Enable table captions, column spans and multi-line cells
+	StripTitle      bool                  // Leave the first h1 out of page bodies
+
+	BaseURL string // Root url of the site, used by the absURL and relURL template functions
+	Layouts string // Directory of templates chosen per page by frontmatter layout, section or default.html
+	Theme   string // Name of a theme in the themes directory, providing layouts, static files and configuration
+
+	Include Patterns // Only copy files other than markdown that match one of these patterns, if there are any
+	Exclude Patterns // Do not copy files other than markdown that match one of these patterns
+
+	Fingerprint Patterns  // Add a content hash to the names of copied files matching these patterns, and rewrite references to them
+	Minify      FileTypes // Types of files to minify: css, js and html, which includes generated pages
+
+	Root string          // Site root directory, containing the configuration file, themes and static files. "" for the working directory
+	Site parser.Metadata // Site configuration, over the theme's configuration. Available to templates as .Site.Config
 }
 
 // Process markdown in src and output to dest using html template
@@ -23,110 +46,336 @@ type InertFlags struct {
 // template: Path to html template file
 // dest: Path to output html file
 func GeneratePage(src, template, dest string) error {
-	var err error
-	var templateStr string
-
-	if template != "" {
-		templateStr, err = ReadFileS(template)
-		if err != nil {
-			return err
-		}
+	page, _, err := loadPage(src, "", template, InertFlags{})
+	if err != nil {
+		return err
 	}
+	page.dest, page.URL = dest, pageURL(filepath.Dir(dest), dest)
 
+	return renderPage(page, newSite([]*Page{page}), newTemplateCache(InertFlags{}))
+}
+
+// Parse the markdown in src into a page and choose its template
+// section: Top level directory of the page within the source directory, used to choose a layout
+func loadPage(src, section, template string, flags InertFlags) (*Page, []parser.Warning, error) {
 	srcTxt, err := ReadFileS(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, err := parser.MDtoHTMLEx(srcTxt, parser.InertParserOptions{
+		File:            src,
+		Frontmatter:     true,
+		Sanitize:        flags.Sanitize,
+		TableClasses:    flags.TableClasses,
+		TableExtensions: flags.TableExtensions,
+		StripTitle:      flags.StripTitle,
+	})
+	if err != nil {
+		return nil, result.Warnings, err
+	}
+
+	result.Metadata = withSectionDefaults(result.Metadata, section, flags.Site)
+	page := newPage(result, src)
+	page.Section = section
+
+	page.template, err = layoutFor(result.Metadata, section, template, layoutDirs(flags))
+	if err != nil {
+		return nil, result.Warnings, fmt.Errorf("%s: %w", src, err)
+	}
+
+	return page, result.Warnings, nil
+}
+
+// Render page with its template and write it to its destination
+func renderPage(page *Page, site *Site, templates *templateCache) error {
+	tmpl, err := templates.get(page.template)
 	if err != nil {
 		return err
 	}
 
-	// Detect and remove YAML frontmatter
-	if strings.HasPrefix(srcTxt, "---\n") {
-		_, body, found := strings.Cut(srcTxt[len("---\n"):], "\n---")
-		if found {
-			srcTxt = body
+	html, err := executeTemplate(tmpl, page, site)
+	if err != nil {
+		return fmt.Errorf("%s: %w", page.Source, err)
+	}
+
+	if len(templates.manifest) > 0 {
+		html = rewriteAssetURLs(html, page.URL, templates.flags.BaseURL, templates.manifest)
+	}
+
+	if minifier := templates.flags.Minify.minifier(page.dest); minifier != nil {
+		size := len(html)
+		html, err = minifier(html)
+		if err != nil {
+			return fmt.Errorf("%s: %w", page.Source, err)
+		}
+
+		if templates.flags.Verbose {
+			fmt.Printf("Minify: %s (%s)\n", page.dest, sizeReport(size, len(html)))
 		}
 	}
-	// TODO: Process YAML frontmatter
 
+	return writePage(page.dest, html)
+}
+
+func writePage(dest, page string) error {
 	destFile, err := CreateAll(dest)
 	defer destFile.Close()
 	if err != nil {
 		return err
 	}
 
-	result, err := parser.MDtoHTML(srcTxt)
+	_, err = destFile.WriteString(page)
+	return err
+}
+
+// Call generatePage with inert flag behaviors
+func GeneratePageEx(src, template, dest string, flags InertFlags) error {
+	var err error
+	flags.Site, err = SiteConfig(flags)
+	if err != nil {
+		return err
+	}
+
+	page, err := loadPageEx(src, "", template, dest, filepath.Dir(dest), flags)
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(
-		dest,
-		[]byte(PopulateTemplate(result.Body, result.Title, templateStr)),
-		0666,
-	)
+	assets, err := staticAssets(filepath.Dir(dest), flags)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := copyAssets(assets, filepath.Dir(dest), flags)
+	return errors.Join(err, writePages([]*Page{page}, manifest, flags))
 }
 
-// Call generatePage with inert flag behaviors
-func GeneratePageEx(src, template, dest string, flags InertFlags) error {
-	if _, err := os.Stat(dest); !errors.Is(err, os.ErrNotExist) {
-		if flags.NoClobber {
-			return nil
+// Load a page with inert flag behaviors, printing its parser warnings
+// destRoot: Output directory that page URLs are relative to
+func loadPageEx(src, section, template, dest, destRoot string, flags InertFlags) (*Page, error) {
+	if flags.PagesAsDirs && filepath.Base(src) != "index.md" {
+		dest = dest[:len(dest)-len(".html")] + "/index.html"
+	}
+
+	page, warnings, err := loadPage(src, section, template, flags)
+	for _, warning := range warnings {
+		fmt.Fprintln(os.Stderr, warning)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if flags.Strict && len(warnings) > 0 {
+		return nil, fmt.Errorf("%s: %d warning(s) treated as errors in strict mode", src, len(warnings))
+	}
+
+	page.dest, page.URL = dest, pageURL(destRoot, dest)
+	return page, nil
+}
+
+// Whether dest can be written, according to the no clobber and interactive flags
+func canOverwrite(dest string, flags InertFlags) (bool, error) {
+	if _, err := os.Stat(dest); errors.Is(err, os.ErrNotExist) {
+		return true, nil
+	}
+
+	if flags.NoClobber {
+		return false, nil
+	}
+
+	if flags.Interactive {
+		fmt.Printf("inertHTML: overwrite '%s'? ", dest)
+		var input string
+		_, err := fmt.Scanln(&input)
+		if input != "y" && input != "yes" {
+			return false, err
 		}
+	}
+
+	return true, nil
+}
 
-		if flags.Interactive {
-			fmt.Printf("inertHTML: overwrite '%s'? ", dest)
-			var input string
-			_, err = fmt.Scanln(&input)
-			if input != "y" && input != "yes" {
-				return err
+// Render and write loaded pages, with all of them available to templates as .Site.Pages
+// manifest: Fingerprinted asset paths that references in the pages are rewritten to
+func writePages(pages []*Page, manifest map[string]string, flags InertFlags) error {
+	site := newSite(pages)
+	templates := newTemplateCache(flags)
+	templates.manifest = manifest
+
+	site.Config = flags.Site
+	site.Title = flags.Site.GetString("title")
+	site.Params, _ = flags.Site["params"].(map[string]any)
+	site.Menus = configMenus(flags.Site)
+
+	var errs []error
+	for _, page := range pages {
+		if ok, err := canOverwrite(page.dest, flags); !ok {
+			if err != nil {
+				errs = append(errs, err)
 			}
+			continue
 		}
-	}
 
-	if flags.PagesAsDirs {
-		info, err := os.Stat(src)
-		if err != nil {
-			return err
+		if flags.Verbose {
+			fmt.Printf("MD -> HTML: %s -> %s\n", page.Source, page.dest)
 		}
 
-		if info.Name() != "index.md" {
-			dest = dest[:len(dest)-len(".html")] + "/index.html"
+		if err := renderPage(page, site, templates); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Copy a file with inert flag behaviors
+// Files of the types in flags.Minify are minified
+func copyFileEx(src, dest string, flags InertFlags) error {
+	if ok, err := canOverwrite(dest, flags); !ok {
+		return err
+	}
+
+	minifier := flags.Minify.minifier(src)
+	if minifier == nil {
+		if flags.Verbose {
+			fmt.Printf("Copy: %s -> %s\n", src, dest)
 		}
+		return FileCopy(src, dest)
+	}
 
+	srcTxt, err := ReadFileS(src)
+	if err != nil {
+		return err
+	}
+
+	result, err := minifier(srcTxt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", src, err)
 	}
 
 	if flags.Verbose {
-		fmt.Printf("MD -> HTML: %s -> %s\n", src, dest)
+		fmt.Printf("Minify: %s -> %s (%s)\n", src, dest, sizeReport(len(srcTxt), len(result)))
 	}
-	return GeneratePage(src, template, dest)
+	return writePage(dest, result)
 }
 
 // Process all markdown files in destination directory
 // If recursive flag is set, continue recursively into subdirectories
+// Drafts, future and expired pages are skipped unless the matching flag is set
+// All pages are loaded before any are written, so templates can list every page in the site
+// Other files, and static files from the site and theme, are copied to dest before pages are written
 // src:		Path to markdown input directory
 // template:	Path to html template file
 // dest:	Path to output directory
 // flags:	Flags that modify generator behavior
 func GenerateDirectory(src, template, dest string, flags InertFlags) error {
+	var err error
+	flags.Site, err = SiteConfig(flags)
+	if err != nil {
+		return err
+	}
+
+	build := &siteBuild{skip: siteFiles(dest, flags)}
+	build.assets, err = staticAssets(dest, flags)
+	if err != nil {
+		return err
+	}
+
+	err = loadDirectory(src, "", template, dest, dest, flags, build)
+
+	manifest, copyErr := copyAssets(build.assets, dest, flags)
+	return errors.Join(err, copyErr, writePages(build.pages, manifest, flags))
+}
+
+// Pages and assets found in the source directory
+type siteBuild struct {
+	pages  []*Page
+	assets []asset
+	skip   map[string]bool // Absolute paths that are not content, see siteFiles
+}
+
+// Absolute paths of the output directory, and the configuration, themes, static files and layouts of the site
+// These are left out of the pages and files found in the source directory, so that the site root can be built
+func siteFiles(dest string, flags InertFlags) map[string]bool {
+	paths := []string{dest, filepath.Join(dest, manifestFile), filepath.Join(flags.Root, manifestFile),
+		filepath.Join(flags.Root, themesDir), filepath.Join(flags.Root, staticDir)}
+	for _, name := range configFiles {
+		paths = append(paths, filepath.Join(flags.Root, name))
+	}
+	if flags.Layouts != "" {
+		paths = append(paths, flags.Layouts)
+	}
+
+	skip := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if abs, err := filepath.Abs(path); err == nil {
+			skip[abs] = true
+		}
+	}
+	return skip
+}
+
+// Load the pages in src and list the files to copy from it, adding them to build
+// section: Top level subdirectory of the source directory that src is in, or "" for the source directory itself
+func loadDirectory(src, section, template, dest, destRoot string, flags InertFlags, build *siteBuild) error {
 	files, err := os.ReadDir(src)
 	if err != nil {
 		return err
 	}
 
+	var errs []error
 	var srcPath, destPath string
 	for _, file := range files {
 		srcPath = filepath.Join(src, file.Name())
 		destPath = filepath.Join(dest, file.Name())
 
+		if abs, absErr := filepath.Abs(srcPath); absErr == nil && build.skip[abs] {
+			continue
+		}
+
 		if flags.Recursive && file.IsDir() {
 			if flags.Verbose {
 				fmt.Printf("Processing directory: %s\n", srcPath)
 			}
-			err = GenerateDirectory(srcPath, template, destPath, flags)
+
+			subSection := section
+			if subSection == "" {
+				subSection = file.Name()
+			}
+			err = loadDirectory(srcPath, subSection, template, destPath, destRoot, flags, build)
 		} else if filepath.Ext(srcPath) == ".md" {
-			destFilePath := destPath[:len(destPath)-len("md")] + "html"
-			err = GeneratePageEx(srcPath, template, destFilePath, flags)
+			var reason string
+			reason, err = unpublishedReason(srcPath, section, flags, time.Now())
+			if err == nil && reason != "" {
+				if flags.Verbose {
+					fmt.Printf("Skipping %s: %s\n", srcPath, reason)
+				}
+				continue
+			}
+
+			if err == nil {
+				var page *Page
+				destFilePath := destPath[:len(destPath)-len("md")] + "html"
+				page, err = loadPageEx(srcPath, section, template, destFilePath, destRoot, flags)
+				if err == nil {
+					build.pages = append(build.pages, page)
+				}
+			}
+		} else if !file.IsDir() && filepath.Clean(srcPath) != filepath.Clean(destPath) {
+			// Files are only copied when the output is written to another directory
+			rel, relErr := filepath.Rel(destRoot, destPath)
+			if relErr == nil && assetIncluded(rel, flags) {
+				build.assets = append(build.assets, asset{src: srcPath, dest: destPath})
+			}
+		}
+
+		// Keep going so that all problems are reported in one run
+		if err != nil {
+			errs = append(errs, err)
+			err = nil
 		}
 	}
 
-	return err
+	return errors.Join(errs...)
 }
@@ -3,8 +3,12 @@ package generator
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/almushel/inertHTML/parser"
 )
 
 func TestValidateTemplateFile(t *testing.T) {
@@ -51,3 +55,304 @@ func TestValidateTemplateFile(t *testing.T) {
 			})
 	}
 }
+
+func TestPopulateTemplate(t *testing.T) {
+	const template = "<title>{{ Title }}</title><meta content=\"{{ Description }}\">{{ Content }}"
+	page := parser.InertParserResult{
+		Title:       "Fish & Chips {{ Content }}",
+		Description: `A "classic"`,
+		Body:        "<p>Body</p>",
+	}
+
+	const expected = "<title>Fish &amp; Chips {{ Content }}</title><meta content=\"A &#34;classic&#34;\"><p>Body</p>"
+	result, err := PopulateTemplateEx(page, template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != expected {
+		t.Fatalf("Expected:\n%s\nResult:\n%s", expected, result)
+	}
+}
+
+func TestUnpublishedPages(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	future := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		metadata  parser.Metadata
+		flags     InertFlags
+		published bool
+	}{
+		{"no metadata", parser.Metadata{}, InertFlags{}, true},
+		{"draft", parser.Metadata{"draft": true}, InertFlags{}, false},
+		{"draft included", parser.Metadata{"draft": true}, InertFlags{Drafts: true}, true},
+		{"not a draft", parser.Metadata{"draft": false}, InertFlags{}, true},
+		{"past date", parser.Metadata{"date": past}, InertFlags{}, true},
+		{"future date", parser.Metadata{"date": future}, InertFlags{}, false},
+		{"quoted future date", parser.Metadata{"date": "2024-04-01"}, InertFlags{}, false},
+		{"future included", parser.Metadata{"date": future}, InertFlags{Future: true}, true},
+		{"publishDate before date", parser.Metadata{"date": future, "publishDate": past}, InertFlags{}, true},
+		{"future publishDate", parser.Metadata{"date": past, "publishDate": future}, InertFlags{}, false},
+		{"expired", parser.Metadata{"expiryDate": past}, InertFlags{}, false},
+		{"expired included", parser.Metadata{"expiryDate": past}, InertFlags{Expired: true}, true},
+		{"not expired", parser.Metadata{"expiryDate": future}, InertFlags{}, true},
+		{"draft with other flags", parser.Metadata{"draft": true}, InertFlags{Future: true, Expired: true}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reason := metadataUnpublishedReason(test.metadata, test.flags, now)
+			if test.published && reason != "" {
+				t.Fatalf("Expected page to be published, skipped because: %s", reason)
+			} else if !test.published && reason == "" {
+				t.Fatalf("Expected page to be skipped")
+			}
+		})
+	}
+}
+
+func TestGenerateDirectorySkipsUnpublished(t *testing.T) {
+	src, dest := t.TempDir(), t.TempDir()
+	pages := map[string]string{
+		"page.md":    "# Page",
+		"draft.md":   "---\ndraft: true\n---\n# Draft",
+		"future.md":  "+++\ndate = 9999-01-01\n+++\n# Future",
+		"expired.md": "{\"expiryDate\": \"2000-01-01\"}\n# Expired",
+	}
+	for name, md := range pages {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(md), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := GenerateDirectory(src, "", dest, InertFlags{Drafts: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string]bool{"page.html": true, "draft.html": true, "future.html": false, "expired.html": false} {
+		_, err := os.Stat(filepath.Join(dest, name))
+		if exists := err == nil; exists != expected {
+			t.Fatalf("Expected %s to exist: %v, got %v", name, expected, exists)
+		}
+	}
+}
+
+func TestLayouts(t *testing.T) {
+	root := t.TempDir()
+	src, dest, layouts := filepath.Join(root, "src"), filepath.Join(root, "dest"), filepath.Join(root, "layouts")
+	files := map[string]string{
+		"src/index.md":          "# Home",
+		"src/landing.md":        "---\nlayout: landing\n---\n# Landing",
+		"src/blog/first.md":     "# First",
+		"src/blog/2024/deep.md": "# Deep",
+		"src/blog/docs.md":      "---\nlayout: docs\n---\n# Docs",
+		"src/docs/intro.md":     "# Intro",
+		"layouts/landing.html":  "landing {{ Title }} {{ Content }}",
+		"layouts/blog.html":     "blog {{ Title }} {{ Content }}",
+		"layouts/docs.html":     "docs {{ Title }} {{ Content }}",
+		"template.html":         "template {{ Title }} {{ Content }}",
+	}
+	writeTestFiles(t, root, files)
+
+	flags := InertFlags{Recursive: true, Layouts: layouts}
+	if err := GenerateDirectory(src, filepath.Join(root, "template.html"), dest, flags); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"index.html":          "template Home",
+		"landing.html":        "landing Landing",
+		"blog/first.html":     "blog First",
+		"blog/2024/deep.html": "blog Deep",
+		"blog/docs.html":      "docs Docs",
+		"docs/intro.html":     "docs Intro",
+	}
+	for name, prefix := range expected {
+		page, err := ReadFileS(filepath.Join(dest, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(page, prefix) {
+			t.Fatalf("%s: expected page to start with %q, got %q", name, prefix, page)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(layouts, "default.html"), []byte("default {{ Title }} {{ Content }}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateDirectory(src, filepath.Join(root, "template.html"), dest, flags); err != nil {
+		t.Fatal(err)
+	}
+	if page, _ := ReadFileS(filepath.Join(dest, "index.html")); !strings.HasPrefix(page, "default Home") {
+		t.Fatalf("Expected default layout, got %q", page)
+	}
+
+	for _, layout := range []string{"missing", "../template"} {
+		_, err := layoutFor(parser.Metadata{"layout": layout}, "", "", []string{layouts})
+		if err == nil {
+			t.Fatalf("Expected error for layout %q", layout)
+		}
+	}
+}
+
+func TestValidateLayoutDir(t *testing.T) {
+	layouts := t.TempDir()
+	if err := os.WriteFile(filepath.Join(layouts, "default.html"), []byte(defaultTemplate), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(layouts, "notes.txt"), []byte("not a template"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateLayoutDir(layouts); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(layouts, "post.html"), []byte("{{ Content }}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateLayoutDir(layouts); err == nil || !strings.Contains(err.Error(), "post.html") {
+		t.Fatalf("Expected an error naming post.html, got %v", err)
+	}
+}
+
+func TestSiteTemplates(t *testing.T) {
+	root := t.TempDir()
+	src, dest := filepath.Join(root, "src"), filepath.Join(root, "dest")
+	files := map[string]string{
+		"src/index.md":       "# Home",
+		"src/blog/first.md":  "---\ndate: 2024-01-01\ntags: [go, web]\n---\n# First\n\n## One\n\n### One A\n\n## Two",
+		"src/blog/second.md": "---\ndate: 2024-02-01\ntags: [go]\n---\n# Second",
+		"src/docs/index.md":  "# Docs",
+		"template.html": `{{ Title }}|{{ range .Site.Pages }}{{ .Title }} {{ .URL }},{{ end }}|` +
+			`{{ range .Site.Taxonomies.tags.go }}{{ .Title }},{{ end }}|{{ len .Site.Sections.blog }}|` +
+			`{{ with .Page.Date }}{{ if not .IsZero }}{{ .Format "2006-01-02" }}{{ end }}{{ end }}|{{ .Page.TableOfContents }}`,
+	}
+	writeTestFiles(t, root, files)
+
+	err := GenerateDirectory(src, filepath.Join(root, "template.html"), dest, InertFlags{Recursive: true, PagesAsDirs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const pages = "Second /blog/second/,First /blog/first/,Docs /docs/,Home /,"
+	expected := map[string]string{
+		"index.html": "Home|" + pages + "|Second,First,|2||" + `<ul><li><a href="#home">Home</a></li></ul>`,
+		"blog/first/index.html": "First|" + pages + "|Second,First,|2|2024-01-01|" +
+			`<ul><li><a href="#first">First</a><ul><li><a href="#one">One</a><ul><li><a href="#one-a">One A</a></li></ul></li>` +
+			`<li><a href="#two">Two</a></li></ul></li></ul>`,
+	}
+	for name, content := range expected {
+		page, err := ReadFileS(filepath.Join(dest, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page != content {
+			t.Fatalf("%s:\nExpected:\n%s\nResult:\n%s", name, content, page)
+		}
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	if _, err := PopulateTemplateEx(parser.InertParserResult{}, "{{ .Page.Title "); err == nil {
+		t.Fatal("Expected a parse error")
+	}
+	if result := PopulateTemplate("", "", "{{ .Page.Title "); result != "{{ .Page.Title " {
+		t.Fatalf("Expected the unpopulated template for an invalid template, got %q", result)
+	}
+	if _, err := PopulateTemplateEx(parser.InertParserResult{}, "{{ .Page.Missing }}"); err == nil {
+		t.Fatal("Expected an execution error")
+	}
+}
+
+// Write files relative to root, creating directories as needed
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPartialsAndBaseLayout(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"src/index.md":   "# Home",
+		"src/post.md":    "---\nlayout: post\n---\n# Post",
+		"src/full.md":    "---\nlayout: full\n---\n# Full",
+		"src/nested.md":  "---\nlayout: nested\n---\n# Nested",
+		"src/missing.md": "---\nlayout: missing-partial\n---\n# Missing",
+		"layouts/baseof.html": "<!DOCTYPE html>\n<html>\n<head><title>{{ block \"title\" . }}{{ Title }}{{ end }}</title></head>\n" +
+			"<body>{{ partial \"header.html\" . }}{{ block \"main\" . }}{{ Content }}{{ end }}</body>\n</html>",
+		"layouts/default.html":           `{{ define "main" }}<main>{{ Content }}</main>{{ end }}`,
+		"layouts/post.html":              `{{ define "title" }}Post: {{ Title }}{{ end }}`,
+		"layouts/full.html":              `full {{ partial "header.html" . }}{{ Content }}`,
+		"layouts/nested.html":            `{{ define "main" }}{{ partial "nav/menu.html" .Site }}{{ end }}`,
+		"layouts/missing-partial.html":   `{{ partial "footer.html" . }}`,
+		"layouts/partials/header.html":   `<header>{{ .Page.Title }}</header>`,
+		"layouts/partials/nav/menu.html": `<nav>{{ range .Pages }}<a href="{{ .URL }}">{{ .Title }}</a>{{ end }}</nav>`,
+	})
+
+	flags := InertFlags{Layouts: filepath.Join(root, "layouts")}
+	err := GenerateDirectory(filepath.Join(root, "src"), "", filepath.Join(root, "dest"), flags)
+	if err == nil || !strings.Contains(err.Error(), "footer.html") {
+		t.Fatalf("Expected an error for the missing partial, got %v", err)
+	}
+
+	expected := map[string]string{
+		"index.html": "<title>Home</title></head>\n<body><header>Home</header><main><h1 id=\"home\">Home</h1></main></body>",
+		"post.html":  "<title>Post: Post</title></head>\n<body><header>Post</header><h1 id=\"post\">Post</h1></body>",
+		"full.html":  "full <header>Full</header><h1 id=\"full\">Full</h1>",
+		"nested.html": `<body><header>Nested</header><nav><a href="/full.html">Full</a><a href="/">Home</a>` +
+			`<a href="/missing.html">Missing</a><a href="/nested.html">Nested</a><a href="/post.html">Post</a></nav></body>`,
+	}
+	for name, content := range expected {
+		page, err := ReadFileS(filepath.Join(root, "dest", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(page, content) {
+			t.Fatalf("%s:\nExpected to contain:\n%s\nResult:\n%s", name, content, page)
+		}
+	}
+}
+
+func TestValidateAssembledTemplates(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"baseof.html":             "<!DOCTYPE html>\n<html>\n<head>{{ partial \"head.html\" . }}</head>\n<body>{{ block \"main\" . }}{{ end }}</body>\n</html>",
+		"partials/head.html":      "<title>{{ .Page.Title }}</title>",
+		"valid.html":              `{{ define "main" }}{{ Content }}{{ end }}`,
+		"no-content.html":         `{{ define "main" }}{{ Title }}{{ end }}`,
+		"recursive.html":          `{{ define "main" }}{{ Content }}{{ partial "recursive.html" . }}{{ end }}`,
+		"partials/recursive.html": `{{ partial "recursive.html" . }}`,
+		"syntax.html":             `{{ define "main" }}{{ Content {{ end }}`,
+	})
+
+	tests := map[string]string{
+		"valid.html":      "",
+		"no-content.html": "{{ Content }} tag not found",
+		"recursive.html":  "nested more than",
+		"syntax.html":     "syntax.html:1",
+	}
+	for file, expected := range tests {
+		t.Run(file, func(t *testing.T) {
+			err := ValidateTemplateFile(filepath.Join(root, file))
+			if expected == "" && err != nil {
+				t.Fatal(err)
+			} else if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+				t.Fatalf("Expected error containing %q, got %v", expected, err)
+			}
+		})
+	}
+
+	writeTestFiles(t, root, map[string]string{"baseof.html": "{{ block \"main\" . }}{{ end }}"})
+	if err := ValidateTemplateFile(filepath.Join(root, "valid.html")); err == nil {
+		t.Fatal("Expected the assembled document to be checked for html tags")
+	}
+}
@@ -0,0 +1,80 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/almushel/inertHTML/parser"
+)
+
+// Name of the layout used for pages without a layout or section layout
+const defaultLayout = "default"
+
+// Find the template for a page in the site and theme layouts
+// Tries the frontmatter layout, then a layout named after the page's section directory, then default.html
+// Falls back to template if no layout applies. A frontmatter layout that does not exist is an error.
+func layoutFor(metadata parser.Metadata, section, template string, dirs []string) (string, error) {
+	if len(dirs) == 0 {
+		return template, nil
+	}
+
+	if layout := metadata.GetString("layout"); layout != "" {
+		if strings.ContainsAny(layout, `/\`) || !filepath.IsLocal(layout) {
+			return "", fmt.Errorf("layout must be the name of a template in the layouts directory, got %q", layout)
+		}
+
+		path, ok := findTemplateFile(dirs, layout+".html")
+		if !ok {
+			return "", fmt.Errorf("layout %q not found in %s", layout, strings.Join(dirs, ", "))
+		}
+		return path, nil
+	}
+
+	for _, name := range []string{section, defaultLayout} {
+		if name == "" {
+			continue
+		}
+
+		if path, ok := findTemplateFile(dirs, name+".html"); ok {
+			return path, nil
+		}
+	}
+
+	return template, nil
+}
+
+// Validate every template in the layouts directory, reporting all invalid templates at once
+// The base layout is only validated as part of the layouts that fill it in
+func ValidateLayoutDir(layouts string) error {
+	return ValidateLayouts(InertFlags{Layouts: layouts})
+}
+
+// Validate the templates in the site and theme layouts that pages can use
+// Theme layouts replaced by a site layout with the same name are skipped
+func ValidateLayouts(flags InertFlags) error {
+	var errs []error
+	validated := make(map[string]bool)
+	for _, dir := range layoutDirs(flags) {
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Failed to open layouts directory: %s", err.Error()))
+			continue
+		}
+
+		for _, file := range files {
+			if file.IsDir() || filepath.Ext(file.Name()) != ".html" || file.Name() == baseLayout || validated[file.Name()] {
+				continue
+			}
+
+			validated[file.Name()] = true
+			if err := ValidateTemplateFileEx(filepath.Join(dir, file.Name()), flags); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,46 @@
+package generator
+
+import (
+	"os"
+	"time"
+
+	"github.com/almushel/inertHTML/parser"
+)
+
+// Returns why the page at src should not be published yet, or "" if it should be
+// Pages are held back while they are drafts, before their publish date and after their expiry date
+// section: Top level directory of the page within the source directory, for section defaults in the site configuration
+func unpublishedReason(src, section string, flags InertFlags, now time.Time) (string, error) {
+	file, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	metadata, err := parser.ParseFrontmatter(file, src)
+	if err != nil {
+		return "", err
+	}
+
+	return metadataUnpublishedReason(withSectionDefaults(metadata, section, flags.Site), flags, now), nil
+}
+
+func metadataUnpublishedReason(metadata parser.Metadata, flags InertFlags, now time.Time) string {
+	if metadata.GetBool("draft") && !flags.Drafts {
+		return "draft (use -drafts to include)"
+	}
+
+	publishDate, ok := metadata.GetTime("publishDate")
+	if !ok {
+		publishDate, ok = metadata.GetTime("date")
+	}
+	if ok && publishDate.After(now) && !flags.Future {
+		return "publish date " + publishDate.Format(time.DateOnly) + " is in the future (use -future to include)"
+	}
+
+	if expiryDate, ok := metadata.GetTime("expiryDate"); ok && !expiryDate.After(now) && !flags.Expired {
+		return "expired on " + expiryDate.Format(time.DateOnly) + " (use -expired to include)"
+	}
+
+	return ""
+}
@@ -0,0 +1,185 @@
+package generator
+
+import (
+	"html/template"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/almushel/inertHTML/parser"
+)
+
+// Frontmatter fields that group pages into taxonomies
+var taxonomies = []string{"tags", "categories"}
+
+// A parsed page, as seen by templates through .Page
+type Page struct {
+	Title       string
+	Description string
+	Content     template.HTML
+	Params      parser.Metadata // All frontmatter values
+	URL         string          // Path of the generated page from the output root, e.g. /blog/first.html
+	Source      string          // Path of the markdown file
+	Section     string          // Top level subdirectory of the source directory, "" for pages in its root
+
+	// Frontmatter dates, zero if not set
+	// Lastmod defaults to the modification time of the markdown file
+	Date, PublishDate, ExpiryDate, Lastmod time.Time
+	Draft                                  bool
+
+	TOC []TOCEntry // Headings nested by level
+
+	dest     string // Output file path
+	template string // Template file path, "" for the default template
+}
+
+type TOCEntry struct {
+	Level    int
+	ID       string
+	Title    string
+	Children []TOCEntry
+}
+
+// All pages in a build, as seen by templates through .Site
+type Site struct {
+	Config     parser.Metadata               // All site configuration values
+	Title      string                        // Site title from the configuration
+	Params     map[string]any                // Values from the params table of the configuration
+	Menus      map[string][]MenuEntry        // Menus from the configuration, by name
+	Pages      []*Page                       // Newest first, then by title
+	Sections   map[string][]*Page            // Pages by section, in the same order as Pages
+	Taxonomies map[string]map[string][]*Page // Pages by taxonomy and term, e.g. .Site.Taxonomies.tags.go
+}
+
+// Data passed to templates
+type templateData struct {
+	Page *Page
+	Site *Site
+}
+
+// Build a page from parser output
+func newPage(result parser.InertParserResult, src string) *Page {
+	page := &Page{
+		Title:       result.Title,
+		Description: result.Description,
+		Content:     template.HTML(result.Body),
+		Params:      result.Metadata,
+		Source:      src,
+		Draft:       result.Metadata.GetBool("draft"),
+		TOC:         buildTOC(result.Headings),
+	}
+
+	page.Date, _ = result.Metadata.GetTime("date")
+	page.PublishDate, _ = result.Metadata.GetTime("publishDate")
+	page.ExpiryDate, _ = result.Metadata.GetTime("expiryDate")
+
+	var ok bool
+	if page.Lastmod, ok = result.Metadata.GetTime("lastmod"); !ok && src != "" {
+		if info, err := os.Stat(src); err == nil {
+			page.Lastmod = info.ModTime()
+		}
+	}
+
+	return page
+}
+
+func newSite(pages []*Page) *Site {
+	site := &Site{
+		Config:     make(parser.Metadata),
+		Menus:      make(map[string][]MenuEntry),
+		Pages:      slices.Clone(pages),
+		Sections:   make(map[string][]*Page),
+		Taxonomies: make(map[string]map[string][]*Page),
+	}
+
+	slices.SortStableFunc(site.Pages, func(a, b *Page) int {
+		if c := b.Date.Compare(a.Date); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Title, b.Title)
+	})
+
+	for _, taxonomy := range taxonomies {
+		site.Taxonomies[taxonomy] = make(map[string][]*Page)
+	}
+
+	for _, page := range site.Pages {
+		if page.Section != "" {
+			site.Sections[page.Section] = append(site.Sections[page.Section], page)
+		}
+
+		for _, taxonomy := range taxonomies {
+			for _, term := range page.Params.GetStrings(taxonomy) {
+				site.Taxonomies[taxonomy][term] = append(site.Taxonomies[taxonomy][term], page)
+			}
+		}
+	}
+
+	return site
+}
+
+// URL of the output file dest, relative to the output root
+// index.html files are addressed by their directory
+func pageURL(root, dest string) string {
+	rel, err := filepath.Rel(root, dest)
+	if err != nil {
+		rel = filepath.Base(dest)
+	}
+
+	url := "/" + filepath.ToSlash(rel)
+	if strings.HasSuffix(url, "/index.html") {
+		url = strings.TrimSuffix(url, "index.html")
+	}
+	return url
+}
+
+// Nest headings under the closest preceding heading of a higher level
+func buildTOC(headings []parser.Heading) []TOCEntry {
+	entries, _ := tocEntries(headings, 0)
+	return entries
+}
+
+// Returns the entries for headings below parentLevel and the number of headings used
+func tocEntries(headings []parser.Heading, parentLevel int) ([]TOCEntry, int) {
+	var entries []TOCEntry
+
+	i := 0
+	for i < len(headings) && headings[i].Level > parentLevel {
+		entry := TOCEntry{Level: headings[i].Level, ID: headings[i].ID, Title: headings[i].Text}
+
+		children, used := tocEntries(headings[i+1:], entry.Level)
+		entry.Children = children
+		entries = append(entries, entry)
+		i += 1 + used
+	}
+
+	return entries, i
+}
+
+// The table of contents as nested lists of links
+func (p *Page) TableOfContents() template.HTML {
+	if len(p.TOC) == 0 {
+		return ""
+	}
+
+	list := tocToHTMLNode(p.TOC)
+	return template.HTML(list.ToHTML())
+}
+
+func tocToHTMLNode(entries []TOCEntry) parser.HtmlNode {
+	list := parser.HtmlNode{Tag: "ul"}
+	for _, entry := range entries {
+		item := parser.HtmlNode{Tag: "li", Children: []parser.HtmlNode{{
+			Tag:   "a",
+			Value: entry.Title,
+			Props: map[string]string{"href": "#" + entry.ID},
+		}}}
+		if len(entry.Children) > 0 {
+			item.Children = append(item.Children, tocToHTMLNode(entry.Children))
+		}
+		list.Children = append(list.Children, item)
+	}
+	return list
+}
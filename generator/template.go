@@ -1,89 +1,198 @@
 package generator
 
 import (
-	"errors"
 	"fmt"
+	"html/template"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"text/template/parse"
+
+	"github.com/almushel/inertHTML/parser"
 )
 
-const defaultTemplate = `<!DOCTYPE html>
-<html>
+// Tags from simple templates, rewritten to their .Page fields before parsing
+var simpleTemplateTag = regexp.MustCompile(`{{\s*(Title|Description|Content)\s*}}`)
 
-<head>
-    <meta charset="utf-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1">
-    <title> {{ Title }} </title>
-</head>
+// Layout that templates made only of {{ define }} blocks are filled into
+// Found next to the template or in the site or theme layouts
+const baseLayout = "baseof.html"
 
-<body>
-{{ Content }}
-</body>
+// Directory of templates for the partial function, next to the template or in the site or theme layouts
+const partialsDir = "partials"
 
-</html>`
+// Limit on partials including partials, so that a partial including itself fails instead of overflowing the stack
+const maxPartialDepth = 32
 
-func ValidateTemplateFile(file string) error {
-	info, err := os.Stat(file)
-	if err != nil {
-		return errors.New(fmt.Sprintf("Failed to open template file: %s", err.Error()))
+// Templates and partials parsed for a build, by file path
+// The default template is stored as ""
+type templateCache struct {
+	flags        InertFlags // Build settings used by template functions
+	templates    map[string]*template.Template
+	partials     map[string]*template.Template
+	partialDepth int
+	manifest     map[string]string // Fingerprinted asset paths, used by the fingerprint function
+}
+
+func newTemplateCache(flags InertFlags) *templateCache {
+	return &templateCache{
+		flags:     flags,
+		templates: make(map[string]*template.Template),
+		partials:  make(map[string]*template.Template),
 	}
+}
+
+// Parse template source with html/template
+// name: File name used in error messages
+// dirs: Directories that partials are loaded from, none if partials are not available
+func (cache *templateCache) parse(name string, dirs []string, src string) (*template.Template, error) {
+	return template.New(name).Funcs(cache.funcs(dirs)).Parse(simpleTemplateTag.ReplaceAllString(src, "{{ .Page.$1 }}"))
+}
 
-	if info.IsDir() {
-		return errors.New("Template file is a directory")
+// Directories searched for the partials and base layout of file, highest priority first
+// The site and theme layouts, preceded by the directory of file if it is not one of them
+func (cache *templateCache) dirs(file string) []string {
+	dirs := layoutDirs(cache.flags)
+	for _, dir := range dirs {
+		if filepath.Clean(dir) == filepath.Dir(file) {
+			return dirs
+		}
 	}
+	return append([]string{filepath.Dir(file)}, dirs...)
+}
 
-	template, err := ReadFileS(file)
-	if err != nil {
-		return errors.New(fmt.Sprintf("Failed to read template file: %s", err.Error()))
+// Find name in the first of dirs that contains it
+func findTemplateFile(dirs []string, name string) (string, bool) {
+	for _, dir := range dirs {
+		file := filepath.Join(dir, name)
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			return file, true
+		}
 	}
-	template = strings.TrimSpace(template)
+	return "", false
+}
 
-	templateTags := []string{
-		"{{ Title }}", "{{ Content }}",
+func (cache *templateCache) get(file string) (*template.Template, error) {
+	if tmpl, ok := cache.templates[file]; ok {
+		return tmpl, nil
 	}
-	for _, tag := range templateTags {
-		if !strings.Contains(template, tag) {
-			return errors.New(fmt.Sprintf("Invalid template: %s tag not found.", tag))
+
+	src, dirs := defaultTemplate, []string(nil)
+	if file != "" {
+		var err error
+		src, err = ReadFileS(file)
+		if err != nil {
+			return nil, err
 		}
+		dirs = cache.dirs(file)
 	}
 
-	htmlTags := []string{
-		"<!DOCTYPE html>",
-		"<html", ">",
-		"<head>", "</head>",
-		"<body>", "</body>",
-		"</html>",
+	tmpl, err := cache.parse(file, dirs, src)
+	if err != nil {
+		return nil, err
 	}
-	for _, tag := range htmlTags {
-		index := strings.Index(template, tag)
-		if index == -1 {
-			return errors.New(fmt.Sprintf("Invalid template: %s tag not found.", tag))
+
+	// A template with nothing outside of {{ define }} blocks overrides the blocks of the base layout
+	if file != "" && (tmpl.Tree == nil || parse.IsEmptyTree(tmpl.Tree.Root)) {
+		base, ok := findTemplateFile(dirs, baseLayout)
+		if !ok {
+			return nil, fmt.Errorf("%s only defines blocks, but no %s was found in %s", file, baseLayout, strings.Join(dirs, ", "))
+		}
+
+		baseSrc, err := ReadFileS(base)
+		if err != nil {
+			return nil, err
+		}
+
+		tmpl, err = cache.parse(base, dirs, baseSrc)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tmpl.New(file).Parse(simpleTemplateTag.ReplaceAllString(src, "{{ .Page.$1 }}"))
+		if err != nil {
+			return nil, err
 		}
-		template = template[index:]
 	}
 
-	return nil
+	cache.templates[file] = tmpl
+	return tmpl, nil
+}
+
+// Render partials/name from the first of dirs that has it with data, for {{ partial "name" . }}
+func (cache *templateCache) partial(dirs []string, name string, data any) (template.HTML, error) {
+	if len(dirs) == 0 {
+		return "", fmt.Errorf("partial %q: partials are only available in template files", name)
+	}
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("partial %q: name must be a path inside the partials directory", name)
+	}
+
+	file, ok := findTemplateFile(dirs, filepath.Join(partialsDir, name))
+	if !ok {
+		return "", fmt.Errorf("partial %q not found in %s", name, strings.Join(dirs, ", "))
+	}
+
+	tmpl, ok := cache.partials[file]
+	if !ok {
+		src, err := ReadFileS(file)
+		if err != nil {
+			return "", err
+		}
+
+		tmpl, err = cache.parse(file, dirs, src)
+		if err != nil {
+			return "", err
+		}
+		cache.partials[file] = tmpl
+	}
+
+	if cache.partialDepth >= maxPartialDepth {
+		return "", fmt.Errorf("partial %q: partials are nested more than %d deep", name, maxPartialDepth)
+	}
+	cache.partialDepth++
+	defer func() { cache.partialDepth-- }()
+
+	var result strings.Builder
+	err := tmpl.Execute(&result, data)
+	return template.HTML(result.String()), err
+}
+
+func executeTemplate(tmpl *template.Template, page *Page, site *Site) (string, error) {
+	var result strings.Builder
+	err := tmpl.Execute(&result, templateData{Page: page, Site: site})
+	return result.String(), err
 }
 
 // Splice body and title into template string
 // body: Body of html page. Inserted at {{ Content }} tag.
-// title: Title of html page. Inserted at {{ Title }} tag.
+// title: Plain text title of html page. Escaped and inserted at {{ Title }} tag.
 // template: (Optional) Template string. If empty, defaultTemplate is used.
+// If the template fails to parse or execute, the error is printed to stderr and template is returned unpopulated
+//
+// Deprecated: Use PopulateTemplateEx, which returns the error.
 func PopulateTemplate(body, title, template string) string {
-	var result string
+	result, err := PopulateTemplateEx(parser.InertParserResult{Body: body, Title: title}, template)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return template
+	}
+	return result
+}
 
+// Render a parsed page with template string, as the only page of its site
+// template: (Optional) Template string. If empty, defaultTemplate is used.
+func PopulateTemplateEx(result parser.InertParserResult, template string) (string, error) {
 	if template == "" {
 		template = defaultTemplate
 	}
 
-	result = strings.Join(
-		strings.Split(template, "{{ Title }}"),
-		title,
-	)
-	result = strings.Join(
-		strings.Split(result, "{{ Content }}"),
-		body,
-	)
+	tmpl, err := newTemplateCache(InertFlags{}).parse("template", nil, template)
+	if err != nil {
+		return "", err
+	}
 
-	return result
+	page := newPage(result, "")
+	return executeTemplate(tmpl, page, newSite([]*Page{page}))
 }
@@ -0,0 +1,69 @@
+package generator
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Directory of themes in the site root
+const themesDir = "themes"
+
+// Directory of static files in the site root and in themes, copied to the root of the output
+const staticDir = "static"
+
+// The built in theme, used when no layout or template applies to a page
+//
+//go:embed theme
+var builtinTheme embed.FS
+
+var defaultTemplate = func() string {
+	data, err := builtinTheme.ReadFile("theme/layouts/default.html")
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}()
+
+// Directory of the theme named in flags, or "" if there is none
+func themeDir(flags InertFlags) string {
+	if flags.Theme == "" {
+		return ""
+	}
+	return filepath.Join(flags.Root, themesDir, flags.Theme)
+}
+
+// Check that the theme named in flags exists
+func ValidateTheme(flags InertFlags) error {
+	if flags.Theme == "" {
+		return nil
+	}
+
+	if strings.ContainsAny(flags.Theme, `/\`) || !filepath.IsLocal(flags.Theme) {
+		return fmt.Errorf("theme must be the name of a directory in %s, got %q", themesDir, flags.Theme)
+	}
+
+	info, err := os.Stat(themeDir(flags))
+	if err != nil {
+		return fmt.Errorf("Failed to open theme: %s", err.Error())
+	} else if !info.IsDir() {
+		return fmt.Errorf("Theme %s is not a directory", themeDir(flags))
+	}
+
+	return nil
+}
+
+// Directories searched for layouts, partials and base layouts, highest priority first
+// Site layouts override theme layouts with the same name
+func layoutDirs(flags InertFlags) []string {
+	var dirs []string
+	if flags.Layouts != "" {
+		dirs = append(dirs, flags.Layouts)
+	}
+	if flags.Theme != "" {
+		dirs = append(dirs, filepath.Join(themeDir(flags), "layouts"))
+	}
+	return dirs
+}
@@ -0,0 +1,77 @@
+package generator
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestThemes(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"src/index.md":    "# Home",
+		"src/post.md":     "---\nlayout: post\n---\n# Post",
+		"src/article.md":  "---\nlayout: article\n---\n# Article",
+		"static/site.css": "site",
+		"static/main.css": "site main",
+
+		"themes/plain/inert.toml": "[params]\ncolor = \"blue\"",
+		"themes/plain/layouts/baseof.html": "<!DOCTYPE html>\n<html>\n<head><title>{{ Title }}</title></head>\n" +
+			"<body>{{ partial \"header.html\" . }}{{ block \"main\" . }}{{ Content }}{{ end }}</body>\n</html>",
+		"themes/plain/layouts/default.html":         `{{ define "main" }}<main class="{{ .Site.Config.params.color }}">{{ Content }}</main>{{ end }}`,
+		"themes/plain/layouts/post.html":            `{{ define "main" }}theme post{{ Content }}{{ end }}`,
+		"themes/plain/layouts/article.html":         `{{ define "main" }}theme article{{ Content }}{{ end }}`,
+		"themes/plain/layouts/partials/header.html": `<header>theme</header>`,
+		"themes/plain/static/main.css":              "theme main",
+		"themes/plain/static/img/logo.svg":          "<svg></svg>",
+
+		"layouts/post.html":            `{{ define "main" }}site post{{ Content }}{{ end }}`,
+		"layouts/partials/header.html": `<header>site</header>`,
+	})
+
+	dest := filepath.Join(root, "dest")
+	flags := InertFlags{Root: root, Theme: "plain", Layouts: filepath.Join(root, "layouts")}
+	if err := ValidateTheme(flags); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateLayouts(flags); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateDirectory(filepath.Join(root, "src"), "", dest, flags); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"index.html":   `<header>site</header><main class="blue"><h1 id="home">Home</h1></main>`,
+		"post.html":    `<header>site</header>site post`,
+		"article.html": `<header>site</header>theme article`,
+		"main.css":     "site main",
+		"site.css":     "site",
+		"img/logo.svg": "<svg></svg>",
+	}
+	for name, content := range expected {
+		result, err := ReadFileS(filepath.Join(dest, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(result, content) {
+			t.Fatalf("%s:\nExpected to contain:\n%s\nResult:\n%s", name, content, result)
+		}
+	}
+
+	for _, name := range []string{"missing", "../plain", ""} {
+		err := ValidateTheme(InertFlags{Root: root, Theme: name})
+		if (err == nil) != (name == "") {
+			t.Fatalf("Theme %q: unexpected validation result %v", name, err)
+		}
+	}
+}
+
+func TestBuiltinTheme(t *testing.T) {
+	result := PopulateTemplate("<p>Body</p>", "Title", "")
+	for _, expected := range []string{"<title> Title </title>", "<style>", "<body>\n<p>Body</p>\n</body>"} {
+		if !strings.Contains(result, expected) {
+			t.Fatalf("Expected the built in theme to contain %q:\n%s", expected, result)
+		}
+	}
+}
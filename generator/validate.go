@@ -0,0 +1,166 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/almushel/inertHTML/parser"
+)
+
+// A problem with the document a template renders
+// Line and Column are 0 if the problem could not be located in File
+type TemplateError struct {
+	File         string
+	Line, Column int
+	Message      string
+}
+
+func (e *TemplateError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// Values rendered in place of the title and content when validating templates
+const (
+	sentinelTitle   = "inertHTML template title"
+	sentinelContent = "<!-- inertHTML template content -->"
+)
+
+// Title and content placeholders in template source and in templates rendered with the sentinel values
+var (
+	sourceTitle     = regexp.MustCompile(`{{[^}]*\bTitle\b[^}]*}}`)
+	sourceContent   = regexp.MustCompile(`{{[^}]*\bContent\b[^}]*}}`)
+	renderedTitle   = regexp.MustCompile(regexp.QuoteMeta(sentinelTitle))
+	renderedContent = regexp.MustCompile(regexp.QuoteMeta(sentinelContent))
+)
+
+// Tags every template must render, in document order
+var documentTags = []string{
+	"<!DOCTYPE html>", "<html>", "<head>", "</head>", "<body>", "</body>", "</html>",
+}
+
+// Index of token in documentTags, or -1 if it is not one of them
+func documentTagIndex(token parser.HTMLToken) int {
+	switch token.Type {
+	case parser.HTMLDirective:
+		fields := strings.Fields(strings.ToLower(strings.Trim(token.Raw, "<!>")))
+		if len(fields) == 2 && fields[0] == "doctype" && fields[1] == "html" {
+			return 0
+		}
+	case parser.HTMLStartTag:
+		switch token.Data {
+		case "html":
+			return 1
+		case "head":
+			return 2
+		case "body":
+			return 4
+		}
+	case parser.HTMLEndTag:
+		switch token.Data {
+		case "head":
+			return 3
+		case "body":
+			return 5
+		case "html":
+			return 6
+		}
+	}
+	return -1
+}
+
+// Find the first problem with the html document in src
+// Returns the byte offset of the problem, or -1 if it has no position, and a description, or "" if there is none
+// title, content: Patterns for the page title and content, which must be inside <body>
+func checkDocument(src string, title, content *regexp.Regexp) (int, string) {
+	bodyStart, bodyEnd := 0, 0
+
+	next := 0
+	for _, token := range parser.TokenizeHTML(src) {
+		i := documentTagIndex(token)
+		if i == -1 {
+			continue
+		} else if i < next {
+			return token.Offset, fmt.Sprintf("unexpected %s after %s", documentTags[i], documentTags[next-1])
+		} else if i > next {
+			return token.Offset, fmt.Sprintf("%s before %s", documentTags[i], documentTags[next])
+		}
+
+		switch documentTags[i] {
+		case "<body>":
+			bodyStart = token.Offset + len(token.Raw)
+		case "</body>":
+			bodyEnd = token.Offset
+		}
+		next++
+	}
+	if next < len(documentTags) {
+		return len(src), fmt.Sprintf("%s tag not found", documentTags[next])
+	}
+
+	loc := content.FindStringIndex(src)
+	if loc == nil {
+		return -1, "{{ Content }} tag not found"
+	} else if loc[0] < bodyStart || loc[1] > bodyEnd {
+		return loc[0], "{{ Content }} is outside of <body>"
+	}
+
+	if title.FindStringIndex(src) == nil {
+		return -1, "{{ Title }} tag not found"
+	}
+
+	return -1, ""
+}
+
+// Check that a template file renders a complete html document with the page title, and content inside <body>
+// Layouts that fill in a base layout are checked as the assembled document
+// Problems with the document are returned as a *TemplateError, located in the template or its base layout where possible
+func ValidateTemplateFile(file string) error {
+	return ValidateTemplateFileEx(file, InertFlags{})
+}
+
+// Validate a template file with the partials and base layouts of the site and theme layouts in flags
+func ValidateTemplateFileEx(file string, flags InertFlags) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Failed to open template file: %s", err.Error()))
+	}
+
+	if info.IsDir() {
+		return errors.New(fmt.Sprintf("Template file %s is a directory", file))
+	}
+
+	tmpl, err := newTemplateCache(flags).get(file)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Invalid template: %s", err.Error()))
+	}
+
+	page := &Page{Title: sentinelTitle, Content: sentinelContent, Params: make(parser.Metadata)}
+	rendered, err := executeTemplate(tmpl, page, newSite([]*Page{page}))
+	if err != nil {
+		return errors.New(fmt.Sprintf("Invalid template: %s", err.Error()))
+	}
+
+	_, message := checkDocument(rendered, renderedTitle, renderedContent)
+	if message == "" {
+		return nil
+	}
+
+	// The rendered document has no useful positions, so look for the same problem in the file the document comes from
+	// Layouts that fill in a base layout are parsed as the base layout
+	templateErr := &TemplateError{File: file, Message: message}
+	if src, err := ReadFileS(tmpl.Name()); err == nil {
+		if offset, srcMessage := checkDocument(src, sourceTitle, sourceContent); srcMessage == message && offset >= 0 {
+			templateErr.File = tmpl.Name()
+			templateErr.Line = strings.Count(src[:offset], "\n") + 1
+			templateErr.Column = offset - strings.LastIndex(src[:offset], "\n")
+		}
+	}
+
+	return templateErr
+}
@@ -0,0 +1,70 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTemplateValidation(t *testing.T) {
+	const head = "<!DOCTYPE html>\n<html>\n<head><title>{{ Title }}</title></head>\n"
+
+	tests := []struct {
+		name, template string
+		expected       string // Error after the file name, "" if the template is valid
+	}{
+		{"attributes", "<!doctype HTML>\n<html lang=\"en\">\n<head><title>{{ Title }}</title></head>\n<body class=\"x\">{{ Content }}</body>\n</html>", ""},
+		{"template actions", head + "<body>{{ if .Page.Title }}<main>{{ .Page.Content }}</main>{{ end }}</body>\n</html>", ""},
+		{"body in comment", head + "<!-- <body> -->\n{{ Content }}\n<!-- </body> -->\n</html>", ":7:1: </html> before <body>"},
+		{"content outside body", head + "{{ Content }}\n<body></body>\n</html>", ":4:1: {{ Content }} is outside of <body>"},
+		{"missing head end", "<!DOCTYPE html>\n<html>\n<head><title>{{ Title }}</title>\n  <body>{{ Content }}</body>\n</html>", ":4:3: <body> before </head>"},
+		{"second body", head + "<body>{{ Content }}</body>\n<body></body>\n</html>", ":5:1: unexpected <body> after </body>"},
+		{"missing doctype", "<html>\n<head><title>{{ Title }}</title></head><body>{{ Content }}</body></html>", ":1:1: <html> before <!DOCTYPE html>"},
+		{"missing title", "<!DOCTYPE html><html><head></head><body>{{ Content }}</body></html>", ": {{ Title }} tag not found"},
+		{"missing content", head + "<body></body>\n</html>", ": {{ Content }} tag not found"},
+	}
+
+	dir := t.TempDir()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := filepath.Join(dir, strings.ReplaceAll(test.name, " ", "-")+".html")
+			if err := os.WriteFile(file, []byte(test.template), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := ValidateTemplateFile(file)
+			if test.expected == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			expected := file + test.expected
+			if err == nil || err.Error() != expected {
+				t.Fatalf("Expected:\n%s\nResult:\n%v", expected, err)
+			}
+		})
+	}
+}
+
+func TestTemplateValidationInBaseLayout(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"baseof.html":        "<!DOCTYPE html>\n<html>\n<head>{{ block \"main\" . }}{{ end }}</head>\n<body></body>\n</html>",
+		"post.html":          `{{ define "main" }}<title>{{ Title }}</title>{{ Content }}{{ end }}`,
+		"partials/head.html": "<head><title>{{ .Page.Title }}</title></head>",
+		"partial-head.html":  "<!DOCTYPE html>\n<html>\n{{ partial \"head.html\" . }}\n<body>{{ Content }}</body>\n</html>",
+	})
+
+	// The base layout has no placeholder to point to, so the error is reported without a position
+	expected := filepath.Join(dir, "post.html") + ": {{ Content }} is outside of <body>"
+	if err := ValidateTemplateFile(filepath.Join(dir, "post.html")); err == nil || err.Error() != expected {
+		t.Fatalf("Expected:\n%s\nResult:\n%v", expected, err)
+	}
+
+	if err := ValidateTemplateFile(filepath.Join(dir, "partial-head.html")); err != nil {
+		t.Fatalf("Expected tags from partials to be accepted, got %v", err)
+	}
+}
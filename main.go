@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -18,7 +19,63 @@ func ErrPrintln(format string) (int, error) {
 	return fmt.Fprintf(os.Stderr, "inertHTML: "+format+"\n")
 }
 
+// Validate template files and layout directories without generating any pages
+// Templates are checked with the partials and base layouts of the -theme and -layouts flags
+// Returns the exit status
+func checkTemplates(args []string) int {
+	var flags generator.InertFlags
+	flagSet := flag.NewFlagSet("check-template", flag.ExitOnError)
+	flagSet.StringVar(&flags.Theme, "theme", "", "name of a theme in the themes directory to take layouts from")
+	flagSet.StringVar(&flags.Layouts, "layouts", "", "directory of html templates with the partials and base layouts of the site")
+	flagSet.Parse(args)
+
+	paths := flagSet.Args()
+	if len(paths) == 0 && flags.Layouts == "" && flags.Theme == "" {
+		ErrPrintln("check-template: template file or layouts directory required")
+		return 2
+	}
+
+	if err := generator.ValidateTheme(flags); err != nil {
+		ErrPrintln(err.Error())
+		return 1
+	}
+
+	var errs []error
+	if len(paths) == 0 {
+		errs = append(errs, generator.ValidateLayouts(flags))
+	}
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			dirFlags := flags
+			dirFlags.Layouts = path
+			errs = append(errs, generator.ValidateLayouts(dirFlags))
+		} else {
+			errs = append(errs, generator.ValidateTemplateFileEx(path, flags))
+		}
+	}
+
+	status := 0
+	for _, err := range errs {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+		}
+	}
+
+	return status
+}
+
 func main() {
+	var command string
+	args := os.Args[1:]
+	if len(args) > 0 && (args[0] == "check-template" || args[0] == "config") {
+		command, args = args[0], args[1:]
+	}
+
+	if command == "check-template" {
+		os.Exit(checkTemplates(args))
+	}
+
 	var err error
 	var flags generator.InertFlags
 	var src, template, dest string
@@ -28,20 +85,63 @@ func main() {
 	flag.BoolVar(&flags.Recursive, "r", false, "process directories and their contents recursively")
 	flag.BoolVar(&flags.Verbose, "v", false, "explain what is being done")
 	flag.BoolVar(&flags.PagesAsDirs, "pagesAsDirs", false, "convert non-index source files to dest/index.html")
+	flag.BoolVar(&flags.Strict, "strict", false, "treat markdown warnings as errors")
+	flag.BoolVar(&flags.Drafts, "drafts", false, "include draft pages when processing directories")
+	flag.BoolVar(&flags.Future, "future", false, "include pages with a future date or publishDate when processing directories")
+	flag.BoolVar(&flags.Expired, "expired", false, "include pages with a past expiryDate when processing directories")
+	flag.BoolVar(&flags.TableClasses, "tableClasses", false, "align table cells with align-* classes instead of inline styles")
+	flag.BoolVar(&flags.TableExtensions, "tableExt", false, "enable table captions, column spans and multi-line cells")
+	flag.BoolVar(&flags.StripTitle, "stripTitle", false, "leave the first h1 out of page bodies, for templates that show the title")
+	flag.Var(&flags.Sanitize, "safe", "sanitize raw html and link urls with policy: none, ugc or strict")
 	flag.StringVar(&dest, "o", "", "write output to file/directory")
 	flag.StringVar(&template, "t", "", "html template for parsed markdown")
-	flag.Parse()
+	flag.StringVar(&flags.BaseURL, "baseURL", "", "root url of the site, e.g. https://example.com/blog/, for absURL and relURL in templates")
+	flag.StringVar(&flags.Theme, "theme", "", "name of a theme in the themes directory to take layouts, static files and configuration from")
+	flag.StringVar(&flags.Layouts, "layouts", "", "directory of html templates chosen by frontmatter layout, section directory or default.html")
+	flag.Var(&flags.Include, "include", "only copy non-markdown files matching this glob pattern (repeatable)")
+	flag.Var(&flags.Exclude, "exclude", "do not copy files matching this glob pattern (repeatable)")
+	flag.Var(&flags.Fingerprint, "fingerprint", "add a content hash to the names of copied files matching this glob pattern (repeatable)")
+	flag.Var(&flags.Minify, "minify", "minify files of these comma separated types: css, js, html (including generated pages)")
+	flag.CommandLine.Parse(args)
+
+	config, configFile, err := generator.LoadConfig(flags.Root)
+	if err == nil {
+		flags.Site, err = generator.ApplyConfig(flag.CommandLine, config, configFile)
+	}
+	if err != nil {
+		ErrPrintln(err.Error())
+		os.Exit(1)
+	}
 
 	if flags.NoClobber {
 		flags.Interactive = false
 	}
 
-	if flag.NArg() < 1 {
+	if command == "config" {
+		config, err = generator.SiteConfig(flags)
+		var out []byte
+		if err == nil {
+			out, err = json.MarshalIndent(config, "", "  ")
+		}
+		if err != nil {
+			ErrPrintln(err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println(string(out))
+		return
+	}
+
+	src = flags.Site.GetString("source")
+	if flag.NArg() > 0 {
+		src = flag.Arg(0)
+	}
+	if src == "" {
 		ErrPrintf("Source file/directory required\n")
 		flag.Usage()
 		os.Exit(1)
 	}
-	src = filepath.Clean(flag.Arg(0))
+	src = filepath.Clean(src)
 
 	srcInfo, err := os.Stat(src)
 	if err != nil {
@@ -83,8 +183,22 @@ func main() {
 		}
 	}
 
+	err = generator.ValidateTheme(flags)
+	if err != nil {
+		ErrPrintln(err.Error())
+		os.Exit(1)
+	}
+
 	if template != "" {
-		err = generator.ValidateTemplateFile(template)
+		err = generator.ValidateTemplateFileEx(template, flags)
+		if err != nil {
+			ErrPrintln(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	if flags.Layouts != "" || flags.Theme != "" {
+		err = generator.ValidateLayouts(flags)
 		if err != nil {
 			ErrPrintln(err.Error())
 			os.Exit(1)
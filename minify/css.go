@@ -0,0 +1,131 @@
+package minify
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Characters that whitespace is never needed next to
+const cssSeparators = "{};,"
+
+// Characters that whitespace is not needed next to in selectors, but is in calc() and other functions
+const cssCombinators = ">+~"
+
+// Remove comments and unneeded whitespace from a stylesheet
+// Strings, url() values and /*! comments are kept as they are
+func CSS(src string) (string, error) {
+	var out []byte
+	var space bool // Whitespace or comments since the last character written
+	var depth int  // Depth of parentheses
+
+	// Write whitespace before the next character, unless neither side needs it
+	writeSpace := func(next byte) {
+		if !space {
+			return
+		}
+		space = false
+
+		if len(out) == 0 {
+			return
+		}
+		last := out[len(out)-1]
+		for _, c := range []byte{last, next} {
+			if strings.IndexByte(cssSeparators, c) >= 0 || (depth == 0 && strings.IndexByte(cssCombinators, c) >= 0) {
+				return
+			}
+		}
+		// Whitespace before a colon separates a descendant selector from a pseudo-class, e.g. a :hover
+		if last == ':' || last == '(' || next == ')' {
+			return
+		}
+		out = append(out, ' ')
+	}
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case isSpace(c):
+			space = true
+			i++
+
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return "", fmt.Errorf("unterminated comment at %s", position(src, i))
+			}
+			end += i + 4
+
+			if strings.HasPrefix(src[i:], "/*!") {
+				writeSpace(c)
+				out = append(out, src[i:end]...)
+			} else {
+				space = true
+			}
+			i = end
+
+		case c == '"' || c == '\'':
+			end, err := scanString(src, i)
+			if err != nil {
+				return "", err
+			}
+			writeSpace(c)
+			out = append(out, src[i:end]...)
+			i = end
+
+		case hasPrefixFold(src[i:], "url(") && (i == 0 || !isCSSNameChar(src[i-1])):
+			// Unquoted urls can contain characters that are otherwise significant, such as // and /*
+			end := strings.IndexByte(src[i:], ')')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated url at %s", position(src, i))
+			}
+			end += i + 1
+
+			value := strings.TrimSpace(src[i+len("url(") : end-1])
+			if value != "" && (value[0] == '"' || value[0] == '\'') {
+				// Quoted urls are handled as strings
+				writeSpace(c)
+				out = append(out, src[i:i+len("url(")]...)
+				depth++
+				i += len("url(")
+				continue
+			}
+
+			writeSpace(c)
+			out = append(out, "url("+value+")"...)
+			i = end
+
+		default:
+			lastSemicolon := len(out) > 0 && out[len(out)-1] == ';'
+			if c == ';' && lastSemicolon {
+				// Empty declaration
+				i++
+				space = false
+				continue
+			}
+			if c == '}' && lastSemicolon {
+				// The last declaration in a block does not need a semicolon
+				out = out[:len(out)-1]
+			}
+
+			writeSpace(c)
+			out = append(out, c)
+			switch c {
+			case '(':
+				depth++
+			case ')':
+				depth = max(depth-1, 0)
+			}
+			i++
+		}
+	}
+
+	return string(out), nil
+}
+
+func isCSSNameChar(c byte) bool {
+	return c == '-' || c == '_' || c >= 0x80 || isAlphaNumeric(c)
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
@@ -0,0 +1,53 @@
+package minify
+
+import "testing"
+
+func TestCSS(t *testing.T) {
+	tests := []struct {
+		name, src, expected string
+	}{
+		{"Whitespace", "body {\n  color: red;\n  margin: 0 auto;\n}\n", "body{color:red;margin:0 auto}"},
+		{"Comments", "/* header */\na { color: red; /* inline */ }", "a{color:red}"},
+		{"CommentBetweenWords", "a/**/b { x: 1 }", "a b{x:1}"},
+		{"LicenseComment", "/*! MIT */\na { x: 1 }", "/*! MIT */ a{x:1}"},
+		{"Selectors", "ul > li + li ~ p,\nh1 h2 { x: 1 }", "ul>li+li~p,h1 h2{x:1}"},
+		{"PseudoClass", "a :hover, a:focus { x: 1 }", "a :hover,a:focus{x:1}"},
+		{"Calc", "a { width: calc(100% - (2 * 1em + 3px)); }", "a{width:calc(100% - (2 * 1em + 3px))}"},
+		{"MediaQuery", "@media screen and (min-width: 600px) { a { x: 1 } }", "@media screen and (min-width:600px){a{x:1}}"},
+		{"Strings", `a::before { content: "  /* not a comment */  " ; font-family: 'A  B', serif }`, `a::before{content:"  /* not a comment */  ";font-family:'A  B',serif}`},
+		{"EscapedQuote", `a { content: "say \"hi\"  " }`, `a{content:"say \"hi\"  "}`},
+		{"URL", "a { background: url( http://example.com/a.png ) }", "a{background:url(http://example.com/a.png)}"},
+		{"QuotedURL", `a { background: url( "a b.png" ) }`, `a{background:url("a b.png")}`},
+		{"EmptyDeclarations", "a { x: 1;; y: 2; }", "a{x:1;y:2}"},
+		{"Important", "a { x: 1 !important }", "a{x:1 !important}"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := CSS(test.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != test.expected {
+				t.Fatalf("\nExpected:\n%s\nResult:\n%s", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestCSSErrors(t *testing.T) {
+	tests := []struct {
+		src, expected string
+	}{
+		{"a { x: 1 } /* open", "unterminated comment at 1:12"},
+		{"a {\n  content: \"open\n}", "unterminated string at 2:12"},
+		{"a { background: url(a.png }", "unterminated url at 1:17"},
+	}
+
+	for _, test := range tests {
+		_, err := CSS(test.src)
+		if err == nil || err.Error() != test.expected {
+			t.Fatalf("%q: expected error %q, result %q", test.src, test.expected, err)
+		}
+	}
+}
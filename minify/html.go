@@ -0,0 +1,251 @@
+package minify
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/almushel/inertHTML/parser"
+)
+
+// Elements that start a new line, so whitespace next to their tags is not shown
+var htmlBlockElements = []string{
+	"address", "article", "aside", "base", "blockquote", "body", "caption", "col", "colgroup",
+	"dd", "details", "dialog", "div", "dl", "dt", "fieldset", "figcaption", "figure", "footer",
+	"form", "h1", "h2", "h3", "h4", "h5", "h6", "head", "header", "hgroup", "hr", "html", "li",
+	"link", "main", "menu", "meta", "nav", "ol", "optgroup", "option", "p", "pre", "section",
+	"summary", "table", "tbody", "td", "tfoot", "th", "thead", "title", "tr", "ul",
+}
+
+// Elements that only format their text, so whitespace on either side of their tags runs together
+var htmlFormattingElements = []string{
+	"a", "abbr", "b", "bdi", "bdo", "cite", "code", "data", "del", "dfn", "em", "i", "ins",
+	"kbd", "mark", "q", "s", "samp", "small", "span", "strong", "sub", "sup", "time", "u", "var",
+	"script", "style", "template",
+}
+
+// Elements whose content is kept as it is, apart from the tags and comments inside pre
+var htmlPreservedElements = []string{"pre", "textarea", "script", "style"}
+
+var htmlVoidElements = []string{
+	"area", "base", "br", "col", "embed", "hr", "img", "input", "link", "meta", "source", "track", "wbr",
+}
+
+// Start tags that close an open p element
+var htmlParagraphClosers = []string{
+	"address", "article", "aside", "blockquote", "details", "div", "dl", "fieldset", "figcaption",
+	"figure", "footer", "form", "h1", "h2", "h3", "h4", "h5", "h6", "header", "hgroup", "hr",
+	"main", "menu", "nav", "ol", "p", "pre", "section", "table", "ul",
+}
+
+// Elements whose end tag can be left out when followed by one of the start tags, or one of the end tags of their parent
+// See: https://html.spec.whatwg.org/multipage/syntax.html#optional-tags
+var htmlOptionalEndTags = map[string]struct{ starts, ends []string }{
+	"li":     {[]string{"li"}, []string{"ul", "ol", "menu"}},
+	"dt":     {[]string{"dt", "dd"}, nil},
+	"dd":     {[]string{"dt", "dd"}, []string{"dl", "div"}},
+	"p":      {htmlParagraphClosers, []string{"article", "aside", "blockquote", "body", "dd", "details", "div", "footer", "form", "header", "li", "main", "nav", "section", "td", "th"}},
+	"option": {[]string{"option", "optgroup"}, []string{"select", "datalist", "optgroup"}},
+	"tr":     {[]string{"tr"}, []string{"tbody", "thead", "tfoot", "table"}},
+	"td":     {[]string{"td", "th"}, []string{"tr"}},
+	"th":     {[]string{"td", "th"}, []string{"tr"}},
+	"thead":  {[]string{"tbody", "tfoot"}, nil},
+	"tbody":  {[]string{"tbody", "tfoot"}, []string{"table"}},
+	"tfoot":  {nil, []string{"table"}},
+	"head":   {[]string{"body"}, nil},
+	"body":   {nil, []string{"html"}},
+	"html":   {nil, nil},
+}
+
+// Output of the first pass, before optional end tags are left out
+type htmlItem struct {
+	tag  string // Tag name, or "" for text, comments and directives
+	end  bool
+	html string
+}
+
+// Remove comments, optional end tags, attribute quotes and whitespace that does not show from a document
+// Whitespace is kept in pre elements, and between inline elements such as <b>a</b> <i>b</i>
+// The content of textarea, script and style elements is kept as it is
+func HTML(src string) (string, error) {
+	tokens := parser.TokenizeHTML(src)
+
+	// Whether a tag is next to whitespace that is not shown, or src starts or ends there
+	blockBoundary := func(i int) bool {
+		if i < 0 || i >= len(tokens) {
+			return true
+		}
+		token := tokens[i]
+		return token.Type == parser.HTMLDirective || (token.Type != parser.HTMLText && slices.Contains(htmlBlockElements, token.Data))
+	}
+
+	var items []htmlItem
+	var preserve string // Name of the preserved element the tokens are in
+	var inHead bool
+	lastSpace := true // Whether the last text written ended with whitespace that the next text can run into
+
+	for i, token := range tokens {
+		// The content of raw text and textarea elements is written as it is, even where it looks like tags or comments
+		if preserve != "" && preserve != "pre" && !(token.Type == parser.HTMLEndTag && token.Data == preserve) {
+			items = append(items, htmlItem{html: token.Raw})
+			continue
+		}
+
+		switch token.Type {
+		case parser.HTMLComment:
+			// Conditional comments are read by old browsers
+			if strings.HasPrefix(token.Raw, "<!--[if") || strings.HasPrefix(token.Raw, "<!--<![endif]") {
+				items = append(items, htmlItem{html: token.Raw})
+			}
+
+		case parser.HTMLDirective:
+			items = append(items, htmlItem{html: token.Raw})
+			lastSpace = true
+
+		case parser.HTMLStartTag, parser.HTMLSelfClosingTag:
+			items = append(items, htmlItem{tag: token.Data, html: minifyTag(token)})
+			if token.Type == parser.HTMLStartTag && slices.Contains(htmlPreservedElements, token.Data) && preserve == "" {
+				preserve = token.Data
+			}
+			switch {
+			case token.Data == "head":
+				inHead = true
+			case token.Data == "body":
+				inHead = false
+			}
+			if slices.Contains(htmlBlockElements, token.Data) {
+				lastSpace = true
+			} else if !slices.Contains(htmlFormattingElements, token.Data) {
+				lastSpace = false
+			}
+
+		case parser.HTMLEndTag:
+			items = append(items, htmlItem{tag: token.Data, end: true, html: "</" + token.Data + ">"})
+			if token.Data == preserve {
+				preserve = ""
+			}
+			if token.Data == "head" {
+				inHead = false
+			}
+			if slices.Contains(htmlBlockElements, token.Data) {
+				lastSpace = true
+			} else if !slices.Contains(htmlFormattingElements, token.Data) {
+				lastSpace = false
+			}
+
+		case parser.HTMLText:
+			text := token.Raw
+			if preserve == "" {
+				text = collapseHTMLSpace(text)
+				if inHead && strings.TrimSpace(text) == "" {
+					text = ""
+				}
+				if lastSpace {
+					text = strings.TrimPrefix(text, " ")
+				}
+				if blockBoundary(nextTag(tokens, i)) {
+					text = strings.TrimSuffix(text, " ")
+				}
+				if text != "" {
+					lastSpace = strings.HasSuffix(text, " ")
+				}
+			}
+
+			if text != "" {
+				items = append(items, htmlItem{html: text})
+			}
+		}
+	}
+
+	var result strings.Builder
+	for i, item := range items {
+		if item.end && optionalEndTag(item.tag, items[i+1:]) {
+			continue
+		}
+		result.WriteString(item.html)
+	}
+
+	return result.String(), nil
+}
+
+// Index of the next token after i that is not a comment, or len(tokens)
+func nextTag(tokens []parser.HTMLToken, i int) int {
+	for i++; i < len(tokens) && tokens[i].Type == parser.HTMLComment; i++ {
+	}
+	return i
+}
+
+// Collapse runs of html whitespace to one space
+// Other whitespace, such as non-breaking spaces, is shown and kept
+func collapseHTMLSpace(text string) string {
+	var result strings.Builder
+	space := false
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case ' ', '\t', '\n', '\r', '\f':
+			space = true
+		default:
+			if space {
+				result.WriteByte(' ')
+				space = false
+			}
+			result.WriteByte(text[i])
+		}
+	}
+	if space {
+		result.WriteByte(' ')
+	}
+	return result.String()
+}
+
+// Whether the end tag of element can be left out before the items that follow it
+func optionalEndTag(element string, next []htmlItem) bool {
+	rule, ok := htmlOptionalEndTags[element]
+	if !ok {
+		return false
+	}
+	if len(next) == 0 {
+		return true
+	}
+	if next[0].tag == "" {
+		return false
+	}
+	if next[0].end {
+		return slices.Contains(rule.ends, next[0].tag)
+	}
+	return slices.Contains(rule.starts, next[0].tag)
+}
+
+// Start tag with lowercase names and attribute values quoted only where needed
+func minifyTag(token parser.HTMLToken) string {
+	var result strings.Builder
+	result.WriteString("<" + token.Data)
+
+	unquoted := false
+	for _, attr := range token.Attrs {
+		result.WriteString(" " + attr.Name)
+		if attr.Value == "" {
+			unquoted = false
+			continue
+		}
+
+		value := strings.ReplaceAll(attr.Value, "&", "&amp;")
+		if strings.ContainsAny(value, " \t\n\r\f\"'=<>`") {
+			result.WriteString(`="` + strings.ReplaceAll(value, `"`, "&#34;") + `"`)
+			unquoted = false
+		} else {
+			result.WriteString("=" + value)
+			unquoted = true
+		}
+	}
+
+	if token.Type == parser.HTMLSelfClosingTag && !slices.Contains(htmlVoidElements, token.Data) {
+		// Self closing tags only matter in svg and math, where a / after an unquoted value would be read as part of it
+		if unquoted {
+			result.WriteByte(' ')
+		}
+		result.WriteByte('/')
+	}
+	result.WriteByte('>')
+
+	return result.String()
+}
@@ -0,0 +1,40 @@
+package minify
+
+import "testing"
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name, src, expected string
+	}{
+		{
+			"Document",
+			"<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n  <meta charset=\"utf-8\">\n  <title>  My   page </title>\n</head>\n<body>\n  <h1 id=\"title\">Title</h1>\n</body>\n</html>\n",
+			`<!DOCTYPE html><html lang=en><head><meta charset=utf-8><title>My page</title><body><h1 id=title>Title</h1>`,
+		},
+		{"Comments", "<p>a <!-- note --> b</p><!--[if IE]><p>IE</p><![endif]-->", "<p>a b</p><!--[if IE]><p>IE</p><![endif]-->"},
+		{"InlineSpace", "<p>\n  <b>bold</b> <i>italic</i>\n  text <a href=\"/\"> link </a> end\n</p>", `<p><b>bold</b> <i>italic</i> text <a href=/>link </a>end`},
+		{"Images", "<p><img src=\"a.png\" alt=\"\"> <img src=\"b.png\"></p>", "<p><img src=a.png alt> <img src=b.png>"},
+		{"NonBreakingSpace", "<p>a&nbsp;   b</p>", "<p>a&nbsp;   b"},
+		{"Pre", "<div>\n<pre>\n  code  <b>bold</b>\n    indented\n</pre>\n</div>", "<div><pre>\n  code  <b>bold</b>\n    indented\n</pre></div>"},
+		{"Textarea", "<p><textarea>\n  a\n\n  b</textarea></p>", "<p><textarea>\n  a\n\n  b</textarea>"},
+		{"Script", "<script>\n  if (a  < b) { x = '  y  ' }\n</script>\n<p>a</p>", "<script>\n  if (a  < b) { x = '  y  ' }\n</script><p>a"},
+		{"Style", "<style>\n  a { color: red; }\n</style>", "<style>\n  a { color: red; }\n</style>"},
+		{"TextareaMarkup", `<textarea><b class="x">  hi</b> <!-- keep --></textarea>`, `<textarea><b class="x">  hi</b> <!-- keep --></textarea>`},
+		{"Quotes", `<a href="/a b" title='say "hi"' data-x="a&amp;b" class="">x</a>`, `<a href="/a b" title="say &#34;hi&#34;" data-x=a&amp;b class>x</a>`},
+		{"OptionalTags", "<ul>\n  <li>a</li>\n  <li>b</li>\n</ul>\n<table>\n<tr><td>1</td><td>2</td></tr>\n</table>", "<ul><li>a<li>b</ul><table><tr><td>1<td>2</table>"},
+		{"RequiredEndTags", "<div><p>a</p>b</div><a><p>c</p></a>", "<div><p>a</p>b</div><a><p>c</p></a>"},
+		{"Svg", `<svg viewBox="0 0 1 1"><path d="M0"/><br/></svg>`, `<svg viewbox="0 0 1 1"><path d=M0 /><br></svg>`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := HTML(test.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != test.expected {
+				t.Fatalf("\nExpected:\n%s\nResult:\n%s", test.expected, result)
+			}
+		})
+	}
+}
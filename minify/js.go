@@ -0,0 +1,269 @@
+package minify
+
+import (
+	"fmt"
+	"strings"
+)
+
+type jsTokenType int
+
+const (
+	jsPunctuator jsTokenType = iota
+	jsName                   // Identifiers and keywords
+	jsNumber
+	jsString // Strings, regular expressions and template literal parts
+	jsComment
+)
+
+type jsToken struct {
+	Type jsTokenType
+	Text string
+
+	Newline bool // Whether a line break comes before the token
+	Space   bool // Whether whitespace or a comment comes before the token
+}
+
+// Keywords that a regular expression, rather than a division, can follow
+var jsRegexpKeywords = []string{
+	"return", "typeof", "instanceof", "in", "of", "new", "delete", "void",
+	"throw", "case", "do", "else", "yield", "await",
+}
+
+// A line break after a punctuator ending with one of these characters never ends a statement
+const jsContinuesAfter = "{([,;:=!&|?*%<>~^."
+
+// A line break before a punctuator starting with one of these characters never ends a statement
+const jsContinuesBefore = "})],;.?:="
+
+// Remove comments and unneeded whitespace from a script
+// Line breaks are kept where they may end a statement by automatic semicolon insertion
+// Strings, template literals, regular expressions and /*! comments are kept as they are
+func JS(src string) (string, error) {
+	tokens, err := tokenizeJS(src)
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	for i, token := range tokens {
+		if i > 0 && token.Space {
+			prev := tokens[i-1]
+			switch {
+			case token.Newline &&
+				(prev.Type != jsPunctuator || strings.IndexByte(jsContinuesAfter, prev.Text[len(prev.Text)-1]) < 0) &&
+				(token.Type != jsPunctuator || strings.IndexByte(jsContinuesBefore, token.Text[0]) < 0):
+				result.WriteByte('\n')
+			case jsNeedsSpace(prev, token):
+				result.WriteByte(' ')
+			}
+		}
+		result.WriteString(token.Text)
+	}
+
+	return result.String(), nil
+}
+
+// Whether two tokens would be read differently without whitespace between them
+func jsNeedsSpace(prev, next jsToken) bool {
+	a, b := prev.Text[len(prev.Text)-1], next.Text[0]
+	switch {
+	case isJSNameChar(a) && isJSNameChar(b):
+		return true
+	case (a == '+' || a == '-') && a == b:
+		// a + +b, a - --b
+		return true
+	case a == '/' && (b == '/' || b == '*'):
+		return true
+	case prev.Type == jsNumber && b == '.':
+		// 1 .toString()
+		return true
+	}
+	return false
+}
+
+func isJSNameChar(c byte) bool {
+	return c == '_' || c == '$' || c == '\\' || c >= 0x80 || isAlphaNumeric(c)
+}
+
+// Split src into tokens, without whitespace and with only /*! comments
+func tokenizeJS(src string) ([]jsToken, error) {
+	var tokens []jsToken
+	var space, newline bool
+	var braces []bool // Open braces, true for those that started a template substitution
+	var prev jsToken  // Last token that is not a comment, with no text at the start
+
+	add := func(tokenType jsTokenType, text string) {
+		token := jsToken{Type: tokenType, Text: text, Space: space, Newline: newline}
+		tokens = append(tokens, token)
+		if tokenType != jsComment {
+			prev = token
+		}
+		space, newline = false, false
+	}
+
+	// A / starts a regular expression where an expression is expected
+	regexpAllowed := func() bool {
+		if prev.Text == "" {
+			return true
+		}
+		switch prev.Type {
+		case jsName:
+			for _, keyword := range jsRegexpKeywords {
+				if prev.Text == keyword {
+					return true
+				}
+			}
+			return false
+		case jsNumber:
+			return false
+		case jsString:
+			// The start of a template substitution, `${ /re/ }`
+			return strings.HasSuffix(prev.Text, "${")
+		}
+		return prev.Text != ")" && prev.Text != "]" && prev.Text != "}"
+	}
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case isSpace(c):
+			space = true
+			newline = newline || c == '\n' || c == '\r'
+			i++
+
+		case strings.HasPrefix(src[i:], "//"):
+			end := strings.IndexAny(src[i:], "\r\n")
+			if end < 0 {
+				end = len(src) - i
+			}
+			space = true
+			i += end
+
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment at %s", position(src, i))
+			}
+			end += i + 4
+
+			space = true
+			newline = newline || strings.ContainsAny(src[i:end], "\r\n")
+			if strings.HasPrefix(src[i:], "/*!") {
+				add(jsComment, src[i:end])
+				space = true
+			}
+			i = end
+
+		case c == '"' || c == '\'':
+			end, err := scanString(src, i)
+			if err != nil {
+				return nil, err
+			}
+			add(jsString, src[i:end])
+			i = end
+
+		case c == '`' || (c == '}' && len(braces) > 0 && braces[len(braces)-1]):
+			if c == '}' {
+				braces = braces[:len(braces)-1]
+			}
+			end, substitution, err := scanTemplate(src, i)
+			if err != nil {
+				return nil, err
+			}
+			if substitution {
+				braces = append(braces, true)
+			}
+			add(jsString, src[i:end])
+			i = end
+
+		case c == '/' && regexpAllowed():
+			end, err := scanRegexp(src, i)
+			if err != nil {
+				return nil, err
+			}
+			add(jsString, src[i:end])
+			i = end
+
+		case c >= '0' && c <= '9' || (c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9'):
+			end := i + 1
+			for end < len(src) {
+				if isJSNameChar(src[end]) || src[end] == '.' {
+					end++
+				} else if (src[end] == '+' || src[end] == '-') && (src[end-1] == 'e' || src[end-1] == 'E') &&
+					!strings.HasPrefix(strings.ToLower(src[i:end]), "0x") {
+					end++
+				} else {
+					break
+				}
+			}
+			add(jsNumber, src[i:end])
+			i = end
+
+		case isJSNameChar(c) || c == '#':
+			end := i + 1
+			for end < len(src) && isJSNameChar(src[end]) {
+				end++
+			}
+			add(jsName, src[i:end])
+			i = end
+
+		default:
+			switch c {
+			case '{':
+				braces = append(braces, false)
+			case '}':
+				if len(braces) > 0 {
+					braces = braces[:len(braces)-1]
+				}
+			}
+			add(jsPunctuator, src[i:i+1])
+			i++
+		}
+	}
+
+	return tokens, nil
+}
+
+// Returns the end of the template literal part starting with the ` or } at src[start]
+// and whether it ends with a ${ substitution
+func scanTemplate(src string, start int) (int, bool, error) {
+	for i := start + 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '`':
+			return i + 1, false, nil
+		case '$':
+			if i+1 < len(src) && src[i+1] == '{' {
+				return i + 2, true, nil
+			}
+		}
+	}
+	return 0, false, fmt.Errorf("unterminated template literal at %s", position(src, start))
+}
+
+// Returns the end of the regular expression starting with the / at src[start], including its flags
+func scanRegexp(src string, start int) (int, error) {
+	inClass := false
+	for i := start + 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '/':
+			if !inClass {
+				i++
+				for i < len(src) && isJSNameChar(src[i]) {
+					i++
+				}
+				return i, nil
+			}
+		case '\n', '\r':
+			return 0, fmt.Errorf("unterminated regular expression at %s", position(src, start))
+		}
+	}
+	return 0, fmt.Errorf("unterminated regular expression at %s", position(src, start))
+}
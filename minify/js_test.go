@@ -0,0 +1,64 @@
+package minify
+
+import "testing"
+
+func TestJS(t *testing.T) {
+	tests := []struct {
+		name, src, expected string
+	}{
+		{"Whitespace", "function add(a, b) {\n    return a + b;\n}\n", "function add(a,b){return a+b;}"},
+		{"Comments", "// line\nlet a = 1; /* block */ let b = 2;", "let a=1;let b=2;"},
+		{"LicenseComment", "/*! MIT */\nlet a = 1;", "/*! MIT */\nlet a=1;"},
+		{"Strings", `let s = "a  // b" + 'c /* d */ ' + "e \" f";`, `let s="a  // b"+'c /* d */ '+"e \" f";`},
+		{"LineContinuation", "let s = 'a \\\n  b';", "let s='a \\\n  b';"},
+		{"Template", "let s = `a  ${ b + `c ${ d }` }  // e`;", "let s=`a  ${b+`c ${d}`}  // e`;"},
+		{"TemplateObject", "let s = `${ {a: 1}.a }  `;", "let s=`${{a:1}.a}  `;"},
+		{"Regexp", "let r = /a \\/ b[/]  /g;", "let r=/a \\/ b[/]  /g;"},
+		{"RegexpAfterKeyword", "return /a  b/.test(s)", "return/a  b/.test(s)"},
+		{"RegexpInTemplate", "let t = `${ / +/.test(x) }`", "let t=`${/ +/.test(x)}`"},
+		{"RegexpAfterOperator", "x = a || /[*]  /.test(s)", "x=a||/[*]  /.test(s)"},
+		{"Division", "x = a / b / c; y = (a) / 2 / d[0] / 3", "x=a/b/c;y=(a)/2/d[0]/3"},
+		{"DivisionAndRegexp", "x = a / /b/.source.length", "x=a/ /b/.source.length"},
+		{"UnaryOperators", "x = a + +b - -c + ++d - --e", "x=a+ +b- -c+ ++d- --e"},
+		{"Postfix", "a++\nb", "a++\nb"},
+		{"Prefix", "a\n++b", "a\n++b"},
+		{"Return", "return\nx", "return\nx"},
+		{"ObjectStatement", "x = {}\nfoo()", "x={}\nfoo()"},
+		{"CallStatement", "a = b\n(c)", "a=b\n(c)"},
+		{"NumberStatement", "x = 1.\nfoo()", "x=1.\nfoo()"},
+		{"ContinuedLines", "x = a ||\n  b\n  .c(\n    d,\n  )", "x=a||b.c(d,)"},
+		{"NumberProperty", "x = 1 .toString() + 1.5.toFixed()", "x=1 .toString()+1.5.toFixed()"},
+		{"Exponent", "x = 1e-3 - 2", "x=1e-3-2"},
+		{"PrivateField", "class A { #a = 1; b() { return this.#a } }", "class A{#a=1;b(){return this.#a}}"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := JS(test.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != test.expected {
+				t.Fatalf("\nExpected:\n%s\nResult:\n%s", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestJSErrors(t *testing.T) {
+	tests := []struct {
+		src, expected string
+	}{
+		{"let a = 1 /* open", "unterminated comment at 1:11"},
+		{"let s = 'open\n'", "unterminated string at 1:9"},
+		{"let s = `open ${ a }", "unterminated template literal at 1:20"},
+		{"let r = /open\n/", "unterminated regular expression at 1:9"},
+	}
+
+	for _, test := range tests {
+		_, err := JS(test.src)
+		if err == nil || err.Error() != test.expected {
+			t.Fatalf("%q: expected error %q, result %v", test.src, test.expected, err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package minify
+
+import (
+	"fmt"
+	"strings"
+)
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isAlphaNumeric(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// Returns the end of the string literal starting with the quote at src[start]
+// Backslashes escape the next character, including line breaks
+func scanString(src string, start int) (int, error) {
+	quote := src[start]
+	for i := start + 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+			if strings.HasPrefix(src[i:], "\r\n") {
+				i++
+			}
+		case quote:
+			return i + 1, nil
+		case '\n':
+			return 0, fmt.Errorf("unterminated string at %s", position(src, start))
+		}
+	}
+	return 0, fmt.Errorf("unterminated string at %s", position(src, start))
+}
+
+// Line and column of offset in src, for error messages
+func position(src string, offset int) string {
+	line := strings.Count(src[:offset], "\n") + 1
+	column := offset - strings.LastIndexByte(src[:offset], '\n')
+	return fmt.Sprintf("%d:%d", line, column)
+}
@@ -1,8 +1,9 @@
 package parser
 
 import (
+	"bufio"
 	"fmt"
-	"html"
+	"io"
 	"strings"
 )
 
@@ -15,64 +16,305 @@ const (
 	blockTypeUnorderedList
 	blockTypeQuote
 	blockTypeTable
+	blockTypeHTML
 )
 
 func IsNumeric(r rune) bool {
 	return r >= '0' && r <= '9'
 }
 
-func ParseMDBlocks(md string) []string {
-	var result []string
+type scannedLine struct {
+	text   string
+	number int
+}
 
-	// NOTE: Dealing with code blocks separately, because they are allowed to break whitespace rules
-	var start, end int
-	for start = 0; start < len(md); start++ {
-		if strings.HasPrefix(md[start:], "```") {
-			for inside := start + 3; inside < len(md); inside++ {
-				if strings.HasPrefix(md[inside:], "\n```") {
-					result = append(result, md[start:inside+4])
+// Reads markdown blocks one at a time from an io.Reader
+type BlockScanner struct {
+	reader    *bufio.Reader
+	block     string
+	blockLine int
+	line      int
+	pending   []scannedLine
+	err       error
+	diag      *diagnostics
+}
 
-					end = inside + 4
-					start = end
+func NewBlockScanner(r io.Reader) *BlockScanner {
+	return &BlockScanner{reader: bufio.NewReader(r)}
+}
 
-					break
+func (s *BlockScanner) nextLine() (string, bool) {
+	if len(s.pending) > 0 {
+		next := s.pending[0]
+		s.pending = s.pending[1:]
+		s.line = next.number
+		return next.text, true
+	}
+
+	if s.err != nil {
+		return "", false
+	}
+
+	line, err := s.reader.ReadString('\n')
+	if err != nil {
+		s.err = err
+		if len(line) == 0 {
+			return "", false
+		}
+	}
+	s.line++
+
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	return line, true
+}
+
+func (s *BlockScanner) unreadLines(lines ...scannedLine) {
+	s.pending = append(lines, s.pending...)
+}
+
+// Detect and skip YAML frontmatter at the start of the document
+// Must be called before the first call to Scan()
+func (s *BlockScanner) SkipFrontmatter() {
+	s.ScanFrontmatter()
+}
+
+// Detect and return frontmatter at the start of the document, without its delimiters
+// The format is "yaml" (between --- lines), "toml" (between +++ lines), "json" (an object
+// whose opening line is { or starts with {") or "" if there is no frontmatter.
+// Must be called before the first call to Scan()
+func (s *BlockScanner) ScanFrontmatter() (string, string) {
+	first, ok := s.nextLine()
+	if !ok {
+		return "", ""
+	}
+
+	lines := []scannedLine{{first, s.line}}
+	switch {
+	case first == "---" || first == "+++":
+		var frontmatter []string
+		for {
+			line, ok := s.nextLine()
+			if !ok {
+				break
+			}
+
+			if line == first || (first == "---" && line == "...") {
+				format := "yaml"
+				if first == "+++" {
+					format = "toml"
 				}
+				return strings.Join(frontmatter, "\n"), format
 			}
-		} else if strings.HasPrefix(md[start:], "\r\n\r\n") {
-			block := strings.TrimSpace(md[end:start])
-			if len(block) > 0 {
-				result = append(result, block)
+			lines = append(lines, scannedLine{line, s.line})
+			frontmatter = append(frontmatter, line)
+		}
+
+	case first == "{" || strings.HasPrefix(first, `{"`):
+		var object jsonObjectScanner
+		for line := first; ; {
+			if object.scan(line) {
+				var frontmatter []string
+				for _, l := range lines {
+					frontmatter = append(frontmatter, l.text)
+				}
+				return strings.Join(frontmatter, "\n"), "json"
 			}
 
-			end = start
-			start += 3
-		} else if strings.HasPrefix(md[start:], "\n\n") {
-			block := strings.TrimSpace(md[end:start])
+			line, ok = s.nextLine()
+			if !ok {
+				break
+			}
+			lines = append(lines, scannedLine{line, s.line})
+		}
+	}
+
+	// Not frontmatter
+	s.unreadLines(lines...)
+	return "", ""
+}
+
+// Tracks nesting across the lines of a JSON object to find where it ends
+type jsonObjectScanner struct {
+	depth    int
+	inString bool
+	escaped  bool
+}
 
-			if len(block) > 0 {
-				result = append(result, block)
+// Returns true if the object is closed by the end of line
+func (o *jsonObjectScanner) scan(line string) bool {
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case o.escaped:
+			o.escaped = false
+		case o.inString && c == '\\':
+			o.escaped = true
+		case c == '"':
+			o.inString = !o.inString
+		case o.inString:
+		case c == '{' || c == '[':
+			o.depth++
+		case c == '}' || c == ']':
+			o.depth--
+			if o.depth == 0 {
+				return true
 			}
+		}
+	}
+
+	return false
+}
+
+// Advance to the next block, which will then be available through Block()
+// Returns false at the end of input or on error
+func (s *BlockScanner) Scan() bool {
+	var lines []string
 
-			end = start
-			start += 1
+	for {
+		line, ok := s.nextLine()
+		if !ok {
+			break
 		}
+
+		// NOTE: Dealing with code blocks separately, because they are allowed to break whitespace rules
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			if len(lines) > 0 {
+				s.unreadLines(scannedLine{line, s.line})
+				break
+			}
+
+			s.blockLine = s.line
+			s.block = s.scanCodeBlock(line)
+			return true
+		}
+
+		if condition := htmlBlockStart(line); condition > 0 {
+			if len(lines) == 0 {
+				s.blockLine = s.line
+				s.block = s.scanHTMLBlock(line, condition)
+				return true
+			} else if condition != htmlBlockParagraphCondition {
+				s.unreadLines(scannedLine{line, s.line})
+				break
+			}
+		}
+
+		if strings.TrimSpace(line) == "" {
+			if len(lines) > 0 {
+				break
+			}
+			continue
+		}
+
+		if len(lines) == 0 {
+			s.blockLine = s.line
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) == 0 {
+		return false
+	}
+
+	s.block = strings.TrimSpace(strings.Join(lines, "\n"))
+	return true
+}
+
+func (s *BlockScanner) scanCodeBlock(opening string) string {
+	var block strings.Builder
+	block.WriteString(strings.TrimSpace(opening))
+
+	for {
+		line, ok := s.nextLine()
+		block.WriteString("\n")
+
+		// Unclosed code blocks continue to the end of the document
+		if !ok {
+			s.diag.warn(s.blockLine, strings.Index(opening, "```")+1, RuleUnclosedCodeFence,
+				"code fence is not closed before the end of the document")
+			break
+		}
+
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			break
+		}
+		block.WriteString(line)
 	}
+	block.WriteString("```")
+
+	return block.String()
+}
+
+// Raw html blocks are kept as-is, including any blank lines allowed by their start condition
+func (s *BlockScanner) scanHTMLBlock(first string, condition int) string {
+	lines := []string{first}
+	endsAtBlankLine := htmlBlockConditions[condition-1].end == nil
 
-	if end < len(md) {
-		block := strings.TrimSpace(md[end:start])
-		if len(block) > 0 {
-			result = append(result, block)
+	for line := first; !htmlBlockEnds(condition, line); {
+		var ok bool
+		line, ok = s.nextLine()
+		if !ok || (endsAtBlankLine && strings.TrimSpace(line) == "") {
+			break
 		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// The most recent block read by Scan()
+func (s *BlockScanner) Block() string {
+	return s.block
+}
+
+// The line number at which the most recent block starts
+func (s *BlockScanner) Line() int {
+	return s.blockLine
+}
+
+// The first non-EOF error encountered by Scan()
+func (s *BlockScanner) Err() error {
+	if s.err == io.EOF {
+		return nil
+	}
+	return s.err
+}
+
+func ParseMDBlocks(md string) []string {
+	var result []string
+
+	blocks := NewBlockScanner(strings.NewReader(md))
+	for blocks.Scan() {
+		result = append(result, blocks.Block())
 	}
 
 	return result
 }
 
+// Split an ordered list item ("1. text") into its number and text
+func cutOrderedListItem(line string) (string, string, bool) {
+	var i int
+	for i < len(line) && IsNumeric(rune(line[i])) {
+		i++
+	}
+
+	if i == 0 || !strings.HasPrefix(line[i:], ". ") {
+		return "", "", false
+	}
+
+	return line[:i], line[i+len(". "):], true
+}
+
 func GetBlockType(block string) int {
 	if block == "" {
 		return blockTypeParagraph
 	}
 
+	if firstLine, _, _ := strings.Cut(block, "\n"); htmlBlockStart(firstLine) > 0 {
+		return blockTypeHTML
+	}
+
 	if block[0] == '#' {
 		// Heading
 		for level, char := range block {
@@ -88,7 +330,7 @@ func GetBlockType(block string) int {
 		// Blockquote
 		var valid bool = true
 		for _, line := range strings.Split(block, "\n") {
-			if line[0] != '>' {
+			if !strings.HasPrefix(line, ">") {
 				valid = false
 				break
 			}
@@ -97,7 +339,7 @@ func GetBlockType(block string) int {
 			return blockTypeQuote
 		}
 		/* TODO: Nested lists */
-	} else if (block[0] == '-' || block[0] == '*') && block[1] == ' ' {
+	} else if strings.HasPrefix(block, "- ") || strings.HasPrefix(block, "* ") {
 		// Unordered List
 		var valid bool = true
 		for _, line := range strings.Split(block, "\n") {
@@ -109,57 +351,13 @@ func GetBlockType(block string) int {
 		if valid {
 			return blockTypeUnorderedList
 		}
-	} else if block[0] == '|' {
-		var bars int
-		for i, line := range strings.Split(strings.TrimSpace(block), "\n") {
-			if i == 0 { // | title1 | title2 |
-				if line[0] != '|' || line[len(line)-1] != '|' {
-					return blockTypeParagraph
-				}
-
-				bars = strings.Count(line, "|")
-			} else if i == 1 { // | :--- | ---: |
-				if line[0] != '|' ||
-					line[len(line)-1] != '|' ||
-					strings.Count(line, "|") != bars {
-					return blockTypeParagraph
-				}
-
-				for _, cell := range strings.Split(strings.Trim(line, "|"), "|") {
-					cell = strings.TrimSpace(cell)
-					if strings.HasPrefix(cell, ":-") {
-						cell = cell[1:]
-					}
-					if strings.HasSuffix(cell, "-:") {
-						cell = cell[:len(cell)-1]
-					}
-
-					if strings.Count(cell, "-") < 3 ||
-						len(strings.Trim(cell, "-")) > 0 {
-						return blockTypeParagraph
-					}
-				}
-			} else { // | content | content |
-				if strings.Count(line, "|") != bars {
-					return blockTypeParagraph
-				}
-			}
-		}
-
-		return blockTypeTable
 	} else if block == "***" || block == "---" || block == "___" {
 		return blockTypeHorizontalRule
-	} else if IsNumeric([]rune(block)[0]) {
+	} else if IsNumeric(rune(block[0])) {
 		// Ordered List
 		var valid bool = true
 		for _, line := range strings.Split(block, "\n") {
-			for i, c := range line {
-				if !IsNumeric(c) {
-					valid = (c == '.' && line[i+1] == ' ')
-					break
-				}
-			}
-			if !valid {
+			if _, _, valid = cutOrderedListItem(line); !valid {
 				break
 			}
 		}
@@ -173,10 +371,14 @@ func GetBlockType(block string) int {
 		}
 	}
 
+	if _, valid := checkTable(block); valid {
+		return blockTypeTable
+	}
+
 	return blockTypeParagraph
 }
 
-func generateValidId(value string) string {
+var idReplacer = func() *strings.Replacer {
 	remove := []string{
 		"`", "[", "]", "(", ")", ":", ";", ".", "?",
 		"=", "+", "%", "^", "$", "#", "@", "!",
@@ -189,187 +391,171 @@ func generateValidId(value string) string {
 		replace = append(replace, r, "")
 	}
 
-	replacer := strings.NewReplacer(replace...)
+	return strings.NewReplacer(replace...)
+}()
 
+func generateValidId(value string) string {
 	return strings.ToLower(
-		html.EscapeString(
-			replacer.Replace(value),
-		),
+		idReplacer.Replace(value),
 	)
 }
 
-func BlocksToHTMLNodes(blocks []string) ([]HtmlNode, error) {
+// Offsets of the start of each line of text, which starts at offset start in its block
+func lineOffsets(text string, start int) []int {
+	offsets := []int{start}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			offsets = append(offsets, start+i+1)
+		}
+	}
+	return offsets
+}
+
+// Convert blocks to html nodes, with warnings for malformed blocks
+// Lines are numbered as if the blocks were separated by blank lines
+func BlocksToHTMLNodes(blocks []string) ([]HtmlNode, []Warning) {
 	var result []HtmlNode
-	var err error
-	var newNode HtmlNode
+	diag := &diagnostics{}
 
+	line := 1
 	for _, block := range blocks {
-		switch GetBlockType(block) {
-
-		case blockTypeHeading:
-			var i int = 0
-			for range block {
-				if block[i] != '#' {
-					break
-				}
-				i++
-			}
-
-			newNode = HtmlNode{
-				Tag:   "h" + fmt.Sprintf("%v", i),
-				Value: block[i+1:],
-			}
-
-			newNode.Props = map[string]string{"id": generateValidId(newNode.Value)}
-			break
-
-		case blockTypeCode:
-			opening, body, _ := strings.Cut(block, "\n")
-			lang, name, _ := strings.Cut(opening[len("```"):], " ")
-			newNode = HtmlNode{
-				Tag: "pre",
-				Value: html.EscapeString(
-					strings.TrimSpace(
-						body[:len(body)-len("```")],
-					),
-				),
-			}
-			newNode.Props = make(map[string]string)
-			if len(lang) > 0 {
-				newNode.Props["class"] = "language-" + lang
-			}
-
-			if len(name) > 0 {
-				newNode.Props["title"] = name
-			}
+		result = append(result, blockToHTMLNode(block, line, diag, InertParserOptions{}))
+		line += strings.Count(block, "\n") + 2
+	}
 
-			break
+	return result, diag.warnings
+}
 
-		case blockTypeQuote:
-			var quoteText string
-			for _, line := range strings.Split(block, "\n") {
-				quoteText += line[3:] + "\n"
-			}
-			newNode = HtmlNode{
-				Tag:   "blockquote",
-				Value: quoteText[:len(quoteText)-1],
-			}
-			break
+// Convert a single block starting at line blockLine, reporting any problems to diag
+func blockToHTMLNode(block string, blockLine int, diag *diagnostics, options InertParserOptions) HtmlNode {
+	var newNode HtmlNode
 
-		case blockTypeOrderedList:
-			newNode = NewHtmlNode("ol", "", nil, nil)
-			for lineNumber, line := range strings.Split(block, "\n") {
-				var i int
-				var c rune
-				for i, c = range line {
-					if !IsNumeric(c) {
-						break
-					}
-				}
-				if lineNumber == 0 {
-					newNode.Props["start"] = line[:i]
-				}
+	blockType := GetBlockType(block)
+	if options.TableExtensions && blockType == blockTypeParagraph {
+		// A caption line before the header row hides the table from GetBlockType
+		if table, _, _ := cutTableCaption(block); GetBlockType(table) == blockTypeTable {
+			blockType = blockTypeTable
+		}
+	}
 
-				newNode.Children = append(newNode.Children, HtmlNode{
-					Tag:   "li",
-					Value: line[i+2:],
-				})
-			}
-			break
+	switch blockType {
 
-		case blockTypeUnorderedList:
-			newNode = HtmlNode{
-				Tag: "ul",
-			}
-			for _, line := range strings.Split(block, "\n") {
-				newNode.Children = append(newNode.Children, HtmlNode{
-					Tag:   "li",
-					Value: line[2:],
-				})
-			}
-			break
-		case blockTypeHorizontalRule:
-			newNode = HtmlNode{
-				Tag: "hr",
+	case blockTypeHeading:
+		var i int = 0
+		for range block {
+			if block[i] != '#' {
+				break
 			}
-			break
+			i++
+		}
 
-		case blockTypeTable:
-			newNode = HtmlNode{
-				Tag:   "div",
-				Props: map[string]string{"style": "overflow-x:auto;"},
-			}
-			table := HtmlNode{
-				Tag: "table",
-			}
+		newNode = HtmlNode{
+			Tag:    "h" + fmt.Sprintf("%v", i),
+			Value:  block[i+1:],
+			source: lineOffsets(block[i+1:], i+1),
+		}
 
-			lines := strings.Split(block, "\n")
-			divider := strings.Split(strings.Trim(lines[1], "|"), "|")
-			var alignments []string
+		newNode.Props = map[string]string{"id": generateValidId(newNode.Value)}
+		break
 
-			for _, cell := range divider {
-				c := strings.TrimSpace(cell)
-				left := strings.HasPrefix(c, ":")
-				right := strings.HasSuffix(c, ":")
+	case blockTypeCode:
+		opening, body, _ := strings.Cut(block, "\n")
+		lang, name, _ := strings.Cut(opening[len("```"):], " ")
 
-				if left && right {
-					alignments = append(alignments, "center")
-				} else if right && !left {
-					alignments = append(alignments, "right")
-				} else {
-					alignments = append(alignments, "left")
-				}
+		if lang == "csv" || lang == "tsv" {
+			var ok bool
+			if newNode, ok = csvBlockToHTMLNode(lang, name, body[:len(body)-len("```")], blockLine, diag, options); ok {
+				break
 			}
+		}
 
-			head := HtmlNode{Tag: "thead"}
-			body := HtmlNode{Tag: "tbody"}
+		newNode = HtmlNode{
+			Tag: "pre",
+			Value: strings.TrimSpace(
+				body[:len(body)-len("```")],
+			),
+		}
+		newNode.Props = make(map[string]string)
+		if len(lang) > 0 {
+			newNode.Props["class"] = "language-" + lang
+		}
 
-			var tag string
-			for i, line := range lines {
-				if i == 1 {
-					continue
-				} else if i == 0 {
-					tag = "th"
-				} else {
-					tag = "td"
-				}
+		if len(name) > 0 {
+			newNode.Props["title"] = name
+		}
 
-				row := HtmlNode{
-					Tag: "tr",
-				}
-				cells := strings.Split(strings.Trim(line, "|"), "|")
-				for i, cell := range cells {
-					row.Children = append(row.Children, HtmlNode{
-						Tag:   tag,
-						Value: strings.TrimSpace(cell),
-						Props: map[string]string{
-							"style": "text-align: " + alignments[i],
-						},
-					})
-				}
+		break
 
-				if i == 0 {
-					head.Children = append(head.Children, row)
-				} else {
-					body.Children = append(body.Children, row)
-				}
+	case blockTypeQuote:
+		lines := strings.Split(block, "\n")
+		source := lineOffsets(block, 0)
+		for i, line := range lines {
+			lines[i] = strings.TrimPrefix(strings.TrimLeft(line, ">"), " ")
+			source[i] += len(line) - len(lines[i])
+		}
+		newNode = HtmlNode{
+			Tag:    "blockquote",
+			Value:  strings.Join(lines, "\n"),
+			source: source,
+		}
+		break
+
+	case blockTypeOrderedList:
+		newNode = NewHtmlNode("ol", "", nil, nil)
+		source := lineOffsets(block, 0)
+		for lineNumber, line := range strings.Split(block, "\n") {
+			number, text, _ := cutOrderedListItem(line)
+			if lineNumber == 0 {
+				newNode.Props["start"] = number
 			}
 
-			table.Children = append(table.Children, head, body)
-			newNode.Children = append(newNode.Children, table)
-
-			break
+			newNode.Children = append(newNode.Children, HtmlNode{
+				Tag:    "li",
+				Value:  text,
+				source: []int{source[lineNumber] + len(line) - len(text)},
+			})
+		}
+		break
 
-		default:
-			newNode = HtmlNode{
-				Tag:   "p",
-				Value: block,
-			}
-			break
+	case blockTypeUnorderedList:
+		newNode = HtmlNode{
+			Tag: "ul",
+		}
+		source := lineOffsets(block, 0)
+		for lineNumber, line := range strings.Split(block, "\n") {
+			newNode.Children = append(newNode.Children, HtmlNode{
+				Tag:    "li",
+				Value:  line[2:],
+				source: []int{source[lineNumber] + 2},
+			})
+		}
+		break
+	case blockTypeHTML:
+		newNode = HtmlNode{
+			Value: block,
+			Raw:   true,
 		}
+		break
 
-		result = append(result, newNode)
+	case blockTypeHorizontalRule:
+		newNode = HtmlNode{
+			Tag: "hr",
+		}
+		break
+
+	case blockTypeTable:
+		table := parseTable(block, blockLine, diag, options.TableExtensions)
+		newNode = table.toHTMLNode(options.TableClasses)
+		break
+
+	default:
+		newNode = HtmlNode{
+			Tag:    "p",
+			Value:  block,
+			source: lineOffsets(block, 0),
+		}
+		break
 	}
 
-	return result, err
+	return newNode
 }
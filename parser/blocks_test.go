@@ -17,6 +17,7 @@ var testBlocks map[string]string = map[string]string{
 	"Unordered List":    "* UL item 1\n- UL item 2\n* UL item 3",
 	"Ordered List":      "1. OL item 1\n2. OL item 2\n3. OL item 3",
 	"Leading Escape":    "\\* This line starts with an escaped asterisk",
+	"HTML Block":        "<div>\n*not emphasis*\n</div>",
 }
 
 func TestMarkdownBlocks(t *testing.T) {
@@ -36,6 +37,27 @@ func TestMarkdownBlocks(t *testing.T) {
 	}
 }
 
+func TestCodeBlockScanning(t *testing.T) {
+	const md = "Paragraph before code\r\n```\nline 1\n\nline 3\n```\nParagraph after code\n\n```\nunclosed"
+	expected := []string{
+		"Paragraph before code",
+		"```\nline 1\n\nline 3\n```",
+		"Paragraph after code",
+		"```\nunclosed\n```",
+	}
+
+	result := ParseMDBlocks(md)
+	if len(result) != len(expected) {
+		t.Fatalf("Incorrect block count in result:\n\n%q", result)
+	}
+
+	for i := range expected {
+		if result[i] != expected[i] {
+			t.Fatalf("Expected block:\n%q\nResult:\n%q", expected[i], result[i])
+		}
+	}
+}
+
 func TestGetBlockType(t *testing.T) {
 	type BlockTest struct {
 		Name   string
@@ -54,6 +76,7 @@ func TestGetBlockType(t *testing.T) {
 		"Unordered List":    blockTypeUnorderedList,
 		"Ordered List":      blockTypeOrderedList,
 		"Leading Escape":    blockTypeParagraph,
+		"HTML Block":        blockTypeHTML,
 	}
 
 	for key, b := range blocks {
@@ -83,6 +106,7 @@ func TestBlockstoHtmlNodes(t *testing.T) {
 		"Unordered List":    {Tag: "ul"},
 		"Ordered List":      {Tag: "ol"},
 		"Leading Escape":    {Tag: "p"},
+		"HTML Block":        {Value: testBlocks["HTML Block"]},
 	}
 
 	for key, b := range blocks {
@@ -97,3 +121,38 @@ func TestBlockstoHtmlNodes(t *testing.T) {
 		})
 	}
 }
+
+func TestBlocksToHTMLNodesWarnings(t *testing.T) {
+	blocks := []string{"Paragraph\ntext", "| a | b |\n| --- | --- |\n| 1 | 2 | 3 |"}
+	_, warnings := BlocksToHTMLNodes(blocks)
+	if len(warnings) != 1 || warnings[0].Rule != RuleTableCellCount || warnings[0].Line != 6 {
+		t.Fatalf("Expected a %s warning on line 6, got %v", RuleTableCellCount, warnings)
+	}
+}
+
+func TestHTMLBlockScanning(t *testing.T) {
+	const md = "<pre>\nline 1\n\nline 3\n</pre>\nParagraph after pre\n" +
+		"<div>\n*not emphasis*\n\n*emphasis*\n\n" +
+		"Paragraph before custom tag\n<custom-tag>\n\n" +
+		"<!-- comment -->\n<script>\nlet x = 1 * 2 * 3;\n</script>"
+	expected := []string{
+		"<pre>\nline 1\n\nline 3\n</pre>",
+		"Paragraph after pre",
+		"<div>\n*not emphasis*",
+		"*emphasis*",
+		"Paragraph before custom tag\n<custom-tag>",
+		"<!-- comment -->",
+		"<script>\nlet x = 1 * 2 * 3;\n</script>",
+	}
+
+	result := ParseMDBlocks(md)
+	if len(result) != len(expected) {
+		t.Fatalf("Incorrect block count in result:\n\n%q", result)
+	}
+
+	for i := range expected {
+		if result[i] != expected[i] {
+			t.Fatalf("Expected block:\n%q\nResult:\n%q", expected[i], result[i])
+		}
+	}
+}
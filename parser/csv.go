@@ -0,0 +1,167 @@
+package parser
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Options from the info string of a csv or tsv fence, e.g. ```csv header=false delimiter=; sortable src=data.csv
+type csvOptions struct {
+	header    bool
+	delimiter rune
+	sortable  bool
+	src       string // File to read instead of the fence body, relative to the markdown file
+}
+
+func parseCSVOptions(lang, info string) (csvOptions, error) {
+	options := csvOptions{header: true, delimiter: ','}
+	if lang == "tsv" {
+		options.delimiter = '\t'
+	}
+
+	for _, field := range strings.Fields(info) {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "header":
+			if value != "true" && value != "false" {
+				return options, fmt.Errorf("header must be true or false, got %q", value)
+			}
+			options.header = value == "true"
+		case "delimiter":
+			switch value {
+			case "tab":
+				options.delimiter = '\t'
+			case "space":
+				options.delimiter = ' '
+			default:
+				if utf8.RuneCountInString(value) != 1 {
+					return options, fmt.Errorf("delimiter must be a single character, tab or space, got %q", value)
+				}
+				options.delimiter, _ = utf8.DecodeRuneInString(value)
+			}
+		case "sortable":
+			options.sortable = true
+		case "src":
+			if !filepath.IsLocal(value) {
+				return options, fmt.Errorf("src must be a relative path inside the markdown file's directory, got %q", value)
+			}
+			options.src = value
+		default:
+			return options, fmt.Errorf("unknown %s option %q", lang, key)
+		}
+	}
+
+	return options, nil
+}
+
+// Parse csv records into a table with left-aligned columns
+// Records are padded or truncated to the width of the first record and warn is called with the source line of each
+func csvToTable(src string, options csvOptions, warn func(line, columns, expected int)) (table, error) {
+	t := table{sortable: options.sortable}
+
+	reader := csv.NewReader(strings.NewReader(src))
+	reader.Comma = options.delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = options.delimiter == '\t'
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return t, err
+		}
+
+		if t.alignments == nil {
+			t.alignments = make([]string, len(record))
+			for i := range t.alignments {
+				t.alignments[i] = "left"
+			}
+		}
+
+		var cells []tableCell
+		for _, field := range record {
+			cells = append(cells, tableCell{text: escapeInline(field), span: 1})
+		}
+
+		cells, columns := fitTableRow(cells, len(t.alignments))
+		if columns != len(t.alignments) {
+			line, _ := reader.FieldPos(0)
+			warn(line, columns, len(t.alignments))
+		}
+
+		if t.header == nil && t.rows == nil && options.header {
+			t.header = cells
+		} else {
+			t.rows = append(t.rows, cells)
+		}
+	}
+
+	if t.alignments == nil {
+		return t, errors.New("no records found")
+	}
+
+	return t, nil
+}
+
+// Render a csv or tsv fence as a table, reporting problems to diag
+// Returns false if the fence could not be rendered and should be shown as code instead
+func csvBlockToHTMLNode(lang, info, body string, blockLine int, diag *diagnostics, options InertParserOptions) (HtmlNode, bool) {
+	csvOpts, err := parseCSVOptions(lang, info)
+	if err != nil {
+		diag.warn(blockLine, 1, RuleCSVTable, "%s", err)
+		return HtmlNode{}, false
+	}
+
+	// Line 1 of the fence body is the line after the opening fence
+	source := "fence"
+	sourceLine := func(line int) int {
+		return blockLine + line
+	}
+
+	if csvOpts.src != "" {
+		if options.File == "" {
+			diag.warn(blockLine, 1, RuleCSVTable, "src is only available when parsing a markdown file")
+			return HtmlNode{}, false
+		}
+		if options.Sanitize != SanitizeNone {
+			diag.warn(blockLine, 1, RuleCSVTable, "src is disabled in safe mode")
+			return HtmlNode{}, false
+		}
+
+		data, err := os.ReadFile(filepath.Join(filepath.Dir(options.File), csvOpts.src))
+		if err != nil {
+			diag.warn(blockLine, 1, RuleCSVTable, "%s", err)
+			return HtmlNode{}, false
+		}
+
+		// Problems in included files are reported at the opening fence
+		body = string(data)
+		source = csvOpts.src
+		sourceLine = func(int) int {
+			return blockLine
+		}
+	}
+
+	t, err := csvToTable(body, csvOpts, func(line, columns, expected int) {
+		diag.warn(sourceLine(line), 1, RuleCSVTable,
+			"%s:%d: record has %d fields, expected %d", source, line, columns, expected)
+	})
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			diag.warn(sourceLine(parseErr.Line), 1, RuleCSVTable, "%s:%d: %s", source, parseErr.Line, parseErr.Err)
+		} else {
+			diag.warn(blockLine, 1, RuleCSVTable, "%s: %s", source, err)
+		}
+		return HtmlNode{}, false
+	}
+
+	return t.toHTMLNode(options.TableClasses), true
+}
@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCSVTables(t *testing.T) {
+	tests := map[string]string{
+		"```csv\nName,Value\n\"a, *b*\",1\n```": `<table><thead><tr>` +
+			`<th style="text-align: left">Name</th><th style="text-align: left">Value</th></tr></thead><tbody><tr>` +
+			`<td style="text-align: left">a, *b*</td><td style="text-align: left">1</td></tr></tbody></table>`,
+		"```tsv header=false\na\tb\n```": `<table><tbody><tr>` +
+			`<td style="text-align: left">a</td><td style="text-align: left">b</td></tr></tbody></table>`,
+		"```csv delimiter=; sortable\nx;y\n1;\"two\nlines\"\n```": `<table class="sortable"><thead><tr>` +
+			`<th class="sortable-column" style="text-align: left">x</th><th class="sortable-column" style="text-align: left">y</th>` +
+			`</tr></thead><tbody><tr><td style="text-align: left">1</td><td style="text-align: left">two<br>lines</td></tr></tbody></table>`,
+	}
+
+	for input, expected := range tests {
+		t.Run(input, func(t *testing.T) {
+			result, err := MDtoHTML(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected = `<div style="overflow-x:auto;">` + expected + "</div>"
+			if result.Body != expected {
+				t.Fatalf("Input:\n%s\nExpected:\n%s\nResult:\n%s", input, expected, result.Body)
+			}
+			if len(result.Warnings) > 0 {
+				t.Fatalf("Unexpected warnings:\n%v", result.Warnings)
+			}
+		})
+	}
+}
+
+func TestCSVInclude(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "data.csv"), []byte("h1,h2\n1,2\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const md = "```csv src=data.csv\n```\n\n```csv src=../secret.csv\n```"
+	result, err := MDtoHTMLEx(md, InertParserOptions{File: filepath.Join(dir, "page.md"), TableClasses: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const table = `<div class="table-wrapper"><table><thead><tr><th class="align-left">h1</th><th class="align-left">h2</th></tr></thead>` +
+		`<tbody><tr><td class="align-left">1</td><td class="align-left">2</td></tr></tbody></table></div>`
+	if !strings.HasPrefix(result.Body, table) {
+		t.Fatalf("Expected:\n%s\nResult:\n%s", table, result.Body)
+	}
+
+	// Paths outside of the markdown file's directory are rendered as code
+	if !strings.HasSuffix(result.Body, `<pre class="language-csv" title="src=../secret.csv"></pre>`) {
+		t.Fatalf("Expected rejected include to be rendered as code:\n%s", result.Body)
+	}
+	if len(result.Warnings) != 1 || result.Warnings[0].Line != 4 || result.Warnings[0].Rule != RuleCSVTable {
+		t.Fatalf("Expected a single %s warning on line 4, got:\n%v", RuleCSVTable, result.Warnings)
+	}
+}
+
+func TestCSVWarnings(t *testing.T) {
+	const md = "# CSV\n\n```csv\na,b\n1,2,3\n\"unclosed\n```\n\n```csv colour=red\n```"
+	expected := []Warning{
+		{Line: 5, Column: 1, Rule: RuleCSVTable},
+		{Line: 6, Column: 1, Rule: RuleCSVTable},
+		{Line: 9, Column: 1, Rule: RuleCSVTable},
+	}
+
+	result, err := MDtoHTML(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Warnings) != len(expected) {
+		t.Fatalf("Expected %d warnings, got %d:\n%v", len(expected), len(result.Warnings), result.Warnings)
+	}
+
+	for i, w := range result.Warnings {
+		e := expected[i]
+		if w.Line != e.Line || w.Column != e.Column || w.Rule != e.Rule {
+			t.Fatalf("Expected warning: %s\nResult: %s", e, w)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Rule ids identifying the kind of problem a Warning describes
+const (
+	RuleUnclosedCodeFence  = "unclosed-code-fence"
+	RuleTableCellCount     = "table-cell-count"
+	RuleUnbalancedEmphasis = "unbalanced-emphasis"
+	RuleCSVTable           = "csv-table"
+)
+
+// A non-fatal problem found in the markdown source
+type Warning struct {
+	File         string
+	Line, Column int
+	Rule         string
+	Message      string
+}
+
+// Compiler-style representation: file:line:column: warning: message [rule]
+func (w Warning) String() string {
+	file := w.File
+	if file == "" {
+		file = "<input>"
+	}
+
+	return fmt.Sprintf("%s:%d:%d: warning: %s [%s]", file, w.Line, w.Column, w.Message, w.Rule)
+}
+
+// Collects warnings for a single source file
+type diagnostics struct {
+	file     string
+	warnings []Warning
+}
+
+// Safe to call on a nil *diagnostics, in which case the warning is discarded
+func (d *diagnostics) warn(line, column int, rule, format string, a ...any) {
+	if d == nil {
+		return
+	}
+
+	d.warnings = append(d.warnings, Warning{
+		File:    d.file,
+		Line:    line,
+		Column:  column,
+		Rule:    rule,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// A warning at an offset in a markdown block, or -1 if its position in the block is not known
+type blockWarning struct {
+	offset        int
+	rule, message string
+}
+
+// Unmatched emphasis delimiters reported for each block, the rest are counted in a single warning
+const maxEmphasisWarnings = 10
+
+// Report warnings found in block starting at blockLine, finding their lines and columns in one pass over the block
+func (d *diagnostics) warnInBlock(block string, blockLine int, warnings []blockWarning) {
+	if d == nil || len(warnings) == 0 {
+		return
+	}
+
+	slices.SortStableFunc(warnings, func(a, b blockWarning) int {
+		return a.offset - b.offset
+	})
+
+	var emphasis, pos, lineStart int
+	line := blockLine
+	for _, w := range warnings {
+		if w.rule == RuleUnbalancedEmphasis {
+			emphasis++
+			if emphasis > maxEmphasisWarnings {
+				continue
+			}
+		}
+
+		if w.offset < 0 {
+			d.warn(blockLine, 1, w.rule, "%s", w.message)
+			continue
+		}
+
+		for ; pos < w.offset && pos < len(block); pos++ {
+			if block[pos] == '\n' {
+				line++
+				lineStart = pos + 1
+			}
+		}
+		d.warn(line, w.offset-lineStart+1, w.rule, "%s", w.message)
+	}
+
+	if emphasis > maxEmphasisWarnings {
+		d.warn(blockLine, 1, RuleUnbalancedEmphasis, "%d more unmatched emphasis delimiters in this block",
+			emphasis-maxEmphasisWarnings)
+	}
+}
@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var htmlEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+)
+
+// See: https://spec.commonmark.org/0.31.2/#entity-and-numeric-character-references
+var entityPattern = regexp.MustCompile(`^&(?:#[xX][0-9a-fA-F]{1,6}|#[0-9]{1,7}|[A-Za-z][A-Za-z0-9]{1,31});`)
+
+// Characters left as-is when normalizing link destinations
+const urlSafeChars = "-_.!~*'();/?:@&=+$,#"
+
+// Escape text for use in html content or a quoted attribute value
+func EscapeHTML(text string) string {
+	return htmlEscaper.Replace(text)
+}
+
+func isHex(c byte) bool {
+	return IsNumeric(rune(c)) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isAlphaNumeric(c byte) bool {
+	return IsNumeric(rune(c)) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// Remove backslashes escaping ASCII punctuation
+func unescapeBackslashes(text string) string {
+	if !strings.Contains(text, "\\") {
+		return text
+	}
+
+	var result strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) && isASCIIPunct(text[i+1]) {
+			i++
+		}
+		result.WriteByte(text[i])
+	}
+
+	return result.String()
+}
+
+// Backslash-escape ASCII punctuation so that text is rendered literally by the inline parser
+func escapeInline(text string) string {
+	var result strings.Builder
+	for i := 0; i < len(text); i++ {
+		if isASCIIPunct(text[i]) {
+			result.WriteByte('\\')
+		}
+		result.WriteByte(text[i])
+	}
+
+	return result.String()
+}
+
+// Resolve escapes and entities in a link destination and percent-encode any unsafe characters
+// Existing percent-encoded sequences are preserved
+func normalizeURL(url string) string {
+	url = html.UnescapeString(unescapeBackslashes(url))
+
+	var result strings.Builder
+	for i := 0; i < len(url); i++ {
+		c := url[i]
+		switch {
+		case c == '%' && i+2 < len(url) && isHex(url[i+1]) && isHex(url[i+2]):
+			result.WriteByte(c)
+		case isAlphaNumeric(c) || strings.IndexByte(urlSafeChars, c) >= 0:
+			result.WriteByte(c)
+		default:
+			fmt.Fprintf(&result, "%%%02X", c)
+		}
+	}
+
+	return result.String()
+}
@@ -0,0 +1,154 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Values parsed from a document's frontmatter
+// Values are string, int, float64, bool, time.Time, []any, map[string]any or nil
+type Metadata map[string]any
+
+// Returns the value of key as a string, or "" if it is missing or a collection
+func (m Metadata) GetString(key string) string {
+	switch value := m[key].(type) {
+	case string:
+		return value
+	case int, float64, bool:
+		return fmt.Sprint(value)
+	case time.Time:
+		return value.Format(time.RFC3339)
+	}
+	return ""
+}
+
+// Returns the value of key as a bool, or false if it is missing or not a bool
+func (m Metadata) GetBool(key string) bool {
+	value, _ := m[key].(bool)
+	return value
+}
+
+// Returns the value of key as a time, including quoted dates
+func (m Metadata) GetTime(key string) (time.Time, bool) {
+	switch value := m[key].(type) {
+	case time.Time:
+		return value, true
+	case string:
+		if t, ok := resolveYAMLScalar(value).(time.Time); ok {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Returns the value of key as a list of strings
+// A single value is treated as a list with one item
+func (m Metadata) GetStrings(key string) []string {
+	values, ok := m[key].([]any)
+	if !ok {
+		if value := m.GetString(key); value != "" {
+			return []string{value}
+		}
+		return nil
+	}
+
+	var result []string
+	for i := range values {
+		if value := (Metadata{"": values[i]}).GetString(""); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// Parse a JSON document whose top level is an object
+// Numbers are converted to int where possible and float64 otherwise
+func ParseJSON(src string) (Metadata, error) {
+	decoder := json.NewDecoder(strings.NewReader(src))
+	decoder.UseNumber()
+
+	lineAt := func(offset int64) int {
+		return strings.Count(src[:min(int(offset), len(src))], "\n") + 1
+	}
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return make(Metadata), &SyntaxError{Line: lineAt(syntaxErr.Offset), Message: syntaxErr.Error()}
+		}
+		return make(Metadata), &SyntaxError{Line: lineAt(decoder.InputOffset()), Message: err.Error()}
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return make(Metadata), &SyntaxError{Line: lineAt(decoder.InputOffset()), Message: "unexpected content after the end of the object"}
+	}
+
+	object, ok := convertJSONNumbers(value).(map[string]any)
+	if !ok {
+		return make(Metadata), &SyntaxError{Line: 1, Message: "top level must be an object"}
+	}
+
+	return Metadata(object), nil
+}
+
+func convertJSONNumbers(value any) any {
+	switch value := value.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(value.String(), 10, 0); err == nil {
+			return int(i)
+		}
+		f, _ := value.Float64()
+		return f
+	case []any:
+		for i := range value {
+			value[i] = convertJSONNumbers(value[i])
+		}
+	case map[string]any:
+		for key := range value {
+			value[key] = convertJSONNumbers(value[key])
+		}
+	}
+	return value
+}
+
+// Read and parse only the frontmatter at the start of src
+// Returns empty metadata if src has no frontmatter
+func ParseFrontmatter(src io.Reader, file string) (Metadata, error) {
+	frontmatter, format := NewBlockScanner(src).ScanFrontmatter()
+	if format == "" {
+		return make(Metadata), nil
+	}
+	return parseFrontmatter(frontmatter, format, file)
+}
+
+// Parse frontmatter in the given format ("yaml", "toml" or "json") as returned by BlockScanner.ScanFrontmatter
+func parseFrontmatter(src, format, file string) (Metadata, error) {
+	var metadata Metadata
+	var err error
+
+	// JSON frontmatter includes its opening line, the others start after their delimiter
+	firstLine := 2
+	switch format {
+	case "toml":
+		metadata, err = ParseTOML(src)
+	case "json":
+		metadata, err = ParseJSON(src)
+		firstLine = 1
+	default:
+		metadata, err = ParseYAML(src)
+	}
+
+	if syntaxErr, ok := err.(*SyntaxError); ok {
+		syntaxErr.File = file
+		syntaxErr.Line += firstLine - 1
+		syntaxErr.Message = "invalid " + format + " frontmatter: " + syntaxErr.Message
+	}
+
+	return metadata, err
+}
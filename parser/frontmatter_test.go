@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFrontmatter(t *testing.T) {
+	const md = "---\ntitle: Pie\ntags:\n  - dessert\n  - baking\ndate: \"2024-03-01\"\n---\n# Heading\n"
+
+	result, err := MDtoHTMLEx(md, InertParserOptions{File: "pie.md", Frontmatter: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Metadata.GetString("title") != "Pie" ||
+		strings.Join(result.Metadata.GetStrings("tags"), ",") != "dessert,baking" {
+		t.Fatalf("Incorrect metadata: %#v", result.Metadata)
+	}
+	if date, ok := result.Metadata.GetTime("date"); !ok || date.Day() != 1 {
+		t.Fatalf("Expected a date, got %#v", result.Metadata["date"])
+	}
+	if result.Body != `<h1 id="heading">Heading</h1>` {
+		t.Fatalf("Frontmatter was not removed from the output:\n%s", result.Body)
+	}
+
+	_, err = MDtoHTMLEx("---\ntitle: Pie\n  bad: indent\n---\n", InertParserOptions{File: "pie.md", Frontmatter: true})
+	if err == nil || !strings.HasPrefix(err.Error(), "pie.md:3: invalid yaml frontmatter") {
+		t.Fatalf("Expected a frontmatter error on line 3, got %v", err)
+	}
+}
+
+func TestFrontmatterFormats(t *testing.T) {
+	expected := Metadata{
+		"title": "Pie",
+		"date":  time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		"tags":  []any{"dessert", "baking"},
+	}
+
+	tests := map[string]string{
+		"yaml": "---\ntitle: Pie\ndate: 2024-03-01\ntags: [dessert, baking]\n---\n",
+		"toml": "+++\ntitle = \"Pie\"\ndate = 2024-03-01\ntags = [\"dessert\", \"baking\"]\n+++\n",
+		"json": "{\n  \"title\": \"Pie\",\n  \"tags\": [\"dessert\", \"baking\"],\n  \"date\": \"2024-03-01\"\n}\n",
+	}
+
+	for format, frontmatter := range tests {
+		t.Run(format, func(t *testing.T) {
+			result, err := MDtoHTMLEx(frontmatter+"# Heading {not json}\n", InertParserOptions{Frontmatter: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result.Body != `<h1 id="heading-not-json">Heading {not json}</h1>` {
+				t.Fatalf("Frontmatter was not removed from the output:\n%s", result.Body)
+			}
+			if result.Metadata.GetString("title") != "Pie" ||
+				!reflect.DeepEqual(result.Metadata["tags"], expected["tags"]) {
+				t.Fatalf("Expected metadata:\n%#v\nResult:\n%#v", expected, result.Metadata)
+			}
+			if date, _ := result.Metadata.GetTime("date"); !date.Equal(expected["date"].(time.Time)) {
+				t.Fatalf("Expected date %v, got %#v", expected["date"], result.Metadata["date"])
+			}
+		})
+	}
+
+	invalid := map[string]string{
+		"+++\ntitle = \"Pie\"\ntitle = \"Tart\"\n+++\n":  "page.md:3: invalid toml frontmatter",
+		"{\n  \"title\": \"Pie\",\n  \"tags\": [,]\n}\n": "page.md:3: invalid json frontmatter",
+	}
+
+	for md, prefix := range invalid {
+		_, err := MDtoHTMLEx(md, InertParserOptions{File: "page.md", Frontmatter: true})
+		if err == nil || !strings.HasPrefix(err.Error(), prefix) {
+			t.Fatalf("Input:\n%s\nExpected error: %s\nResult: %v", md, prefix, err)
+		}
+	}
+
+	// Documents that only look like the start of frontmatter are rendered as markdown
+	for _, md := range []string{"+++\nno closing delimiter", "{not json}", "{\"unclosed\": 1"} {
+		result, err := MDtoHTMLEx(md, InertParserOptions{Frontmatter: true})
+		if err != nil || !strings.HasPrefix(result.Body, "<p>") {
+			t.Fatalf("Input:\n%s\nExpected a paragraph, got %q (%v)", md, result.Body, err)
+		}
+	}
+
+	// Frontmatter is only parsed when asked for
+	result, err := MDtoHTML(tests["yaml"] + "text")
+	if err != nil || len(result.Metadata) > 0 || !strings.Contains(result.Body, "title: Pie") {
+		t.Fatalf("Expected frontmatter to be rendered as markdown, got %q %v (%v)", result.Body, result.Metadata, err)
+	}
+}
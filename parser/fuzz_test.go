@@ -0,0 +1,146 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+var fuzzSeeds = []string{
+	testDocument,
+	"-",
+	"*",
+	"1",
+	"1.",
+	"> quote\n\n> quote",
+	"| a | b |\n| --- | --- |\n| 1 | 2 | 3 |",
+	"| a |",
+	"```",
+	"**bold *and italic***",
+	"[link](url) ![image](src)",
+	"<div>\n*text*\n</div>",
+	"<a href=\"javascript:x\" onclick='y'>a</a><script>z</script>",
+	"+++\ntitle = \"a\"\n+++\ntext",
+	"{\n\"title\": \"a\"\n}\ntext",
+}
+
+func FuzzParseMDBlocks(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, md string) {
+		for _, block := range ParseMDBlocks(md) {
+			GetBlockType(block)
+		}
+	})
+}
+
+func FuzzSplitAll(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, text string) {
+		nodes := TextNodeSlice{{TextType: textTypeText, Text: text}}
+		nodes, err := nodes.SplitAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, node := range nodes {
+			if _, err := node.ToHTMLNode(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+}
+
+func FuzzMDtoHTML(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, md string) {
+		if _, err := MDtoHTML(md); err != nil {
+			t.Fatal(err)
+		}
+
+		// Blocks that could not come from ParseMDBlocks
+		BlocksToHTMLNodes([]string{md})
+	})
+}
+
+// Inputs that previously caused panics or excessive parse times
+// Also kept in testdata/fuzz/FuzzMDtoHTML as the starting corpus for go test -fuzz
+func TestMalformedInput(t *testing.T) {
+	inputs := map[string]string{
+		"Lone dash":               "-",
+		"Lone asterisk":           "*",
+		"Lone number":             "1",
+		"Number and period":       "1.",
+		"Number list item":        "1. item\n2",
+		"Empty quote line":        "> quote\n\n> quote",
+		"Table row too long":      "| a | b |\n| --- | --- |\n| 1 | 2 | 3 |",
+		"Single table row":        "| a |",
+		"Nested brackets":         strings.Repeat("[", 10000),
+		"Unclosed link urls":      strings.Repeat("[a](b ", 10000),
+		"Unmatched backtick runs": strings.Repeat("`a``", 10000),
+		"Unmatched delimiters":    strings.Repeat("_a_ *", 10000),
+	}
+
+	for name, md := range inputs {
+		t.Run(name, func(t *testing.T) {
+			if _, err := MDtoHTML(md); err != nil {
+				t.Fatal(err)
+			}
+			BlocksToHTMLNodes([]string{md})
+		})
+	}
+}
+
+func FuzzSanitizeHTML(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, src string) {
+		result := SanitizeHTML(src, SanitizeUGC)
+		if strings.Contains(strings.ToLower(result), "<script") {
+			t.Fatalf("Script tag in sanitized output:\n%q", result)
+		}
+		if again := SanitizeHTML(result, SanitizeUGC); again != result {
+			t.Fatalf("Sanitizing is not idempotent:\n%q\n%q", result, again)
+		}
+	})
+}
+
+func FuzzParseYAML(f *testing.F) {
+	seeds := []string{
+		"title: a\ntags: [a, 'b', {c: d}]",
+		"a:\n  - b: c\n    d: |\n      text\n  - - e",
+		"a: >-\n  folded\n\n  text\nb: \"\\u00e9\"",
+		"- a\n-\n  - b",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, src string) {
+		ParseYAML(src)
+	})
+}
+
+func FuzzParseTOML(f *testing.F) {
+	seeds := []string{
+		"title = \"a\"\ntags = ['a', \"b\"]\n[t]\nk.v = {x = 1}",
+		"[[a]]\nb = 2024-01-01 10:00:00Z\n[[a]]\nc = \"\"\"\nx \\\n y\"\"\"",
+		"a = [\n  1, # one\n  [2],\n]",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, src string) {
+		ParseTOML(src)
+	})
+}
@@ -2,30 +2,54 @@ package parser
 
 import (
 	"fmt"
+	"io"
 	"slices"
+	"sort"
 	"strings"
 )
 
+var mdUnescaper = strings.NewReplacer(
+	"\\*", "*",
+	"\\_", "_",
+	"\\`", "`",
+	"\\\\", "\\",
+)
+
 type HtmlNode struct {
 	Tag, Value string
 	Children   []HtmlNode
 	Props      map[string]string
+	Raw        bool // Value is html to be written without escaping
+
+	source []int // Offset in the markdown block of the start of each line of Value, nil if Value is not taken from it
 }
 
 func (node *HtmlNode) ProcessInnerText() {
-	if node.Tag == "pre" || node.Tag == "code" {
+	node.processInnerText(nil)
+}
+
+// warn is called with the offset in the markdown block of any problem found, or -1 if it is not known
+func (node *HtmlNode) processInnerText(warn func(offset int, rule, message string)) {
+	if node.Raw || node.Tag == "pre" || node.Tag == "code" {
 		return
 	}
 
+	// NOTE: Children created from inline markdown below are already fully parsed
+	for i := range node.Children {
+		node.Children[i].processInnerText(warn)
+	}
+
 	if node.Value != "" {
 		var innerTextNodes TextNodeSlice
-		innerTextNodes = append(innerTextNodes, TextNode{
-			TextType: textTypeText,
-			Text:     node.Value,
-		})
+		var inlineWarn inlineWarnFunc
+		if warn != nil {
+			inlineWarn = node.sourceWarn(warn)
+		}
 
-		innerTextNodes, _ = innerTextNodes.SplitAll()
-		if len(innerTextNodes) > 1 || innerTextNodes[0].TextType != textTypeText {
+		innerTextNodes = parseInline(node.Value, inlineWarn)
+		if len(innerTextNodes) == 0 {
+			node.Value = ""
+		} else if len(innerTextNodes) > 1 || innerTextNodes[0].TextType != textTypeText {
 			for _, itNode := range innerTextNodes {
 				child, _ := itNode.ToHTMLNode()
 				node.Children = append(node.Children, child)
@@ -36,44 +60,105 @@ func (node *HtmlNode) ProcessInnerText() {
 			node.Value = innerTextNodes[0].Text
 		}
 	}
+}
 
-	if len(node.Children) > 0 {
-		for i := range node.Children {
-			node.Children[i].ProcessInnerText()
+// Convert offsets in Value to offsets in the markdown block it was taken from
+func (node *HtmlNode) sourceWarn(warn func(offset int, rule, message string)) inlineWarnFunc {
+	value, source := node.Value, node.source
+	var lines []int // Offset of the start of each line of value, found on the first warning
+
+	return func(offset int, rule, message string) {
+		if lines == nil {
+			lines = lineOffsets(value, 0)
+		}
+
+		line := sort.SearchInts(lines, offset+1) - 1
+		if line >= len(source) {
+			warn(-1, rule, message)
+			return
 		}
+		warn(source[line]+offset-lines[line], rule, message)
 	}
 }
 
+// Plain text content of node and its children, with image alt text and without raw html
+func (node *HtmlNode) TextContent() string {
+	var result strings.Builder
+	node.writeTextContent(&result)
+	return result.String()
+}
+
+func (node *HtmlNode) writeTextContent(result *strings.Builder) {
+	if node.Raw {
+		return
+	}
+
+	if node.Tag == "img" {
+		result.WriteString(node.Props["alt"])
+	}
+	result.WriteString(node.Value)
+
+	for i := range node.Children {
+		node.Children[i].writeTextContent(result)
+	}
+}
+
+// Remove markdown backslash escapes from node and its children
+//
+// Deprecated: Escapes are now resolved while parsing inline text, so nodes from MDtoHTML
+// have none left. Calling this on them removes backslashes that are part of the text.
 func (node *HtmlNode) UnescapeMD() {
-	replacer := strings.NewReplacer(
-		"\\*", "*",
-		"\\_", "_",
-		"\\`", "`",
-		"\\\\", "\\",
-	)
+	// Backslash escapes are literal inside of code
+	if node.Raw || node.Tag == "pre" || node.Tag == "code" {
+		return
+	}
 
-	node.Value = replacer.Replace(node.Value)
+	node.Value = mdUnescaper.Replace(node.Value)
 
 	for i := range node.Children {
 		node.Children[i].UnescapeMD()
 	}
 }
 
-func (node *HtmlNode) ToHTML() string {
-	var result string
+// Elements that have no content or closing tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// Render node and its children to w
+func (node *HtmlNode) WriteHTML(w io.Writer) error {
+	value := node.Value
+	if !node.Raw {
+		value = EscapeHTML(value)
+	}
 
 	if node.Tag == "" {
-		result = node.Value
-	} else {
-		var children string = node.Value
-		for _, child := range node.Children {
-			children += child.ToHTML()
-		}
+		_, err := io.WriteString(w, value)
+		return err
+	}
 
-		result = fmt.Sprintf("<%s%s>%s</%s>", node.Tag, node.PropsToHTML(), children, node.Tag)
+	_, err := fmt.Fprintf(w, "<%s%s>%s", node.Tag, node.PropsToHTML(), value)
+	if err != nil || voidElements[node.Tag] {
+		return err
 	}
 
-	return result
+	for i := range node.Children {
+		err = node.Children[i].WriteHTML(w)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(w, "</%s>", node.Tag)
+	return err
+}
+
+func (node *HtmlNode) ToHTML() string {
+	var result strings.Builder
+	node.WriteHTML(&result)
+
+	return result.String()
 }
 
 func (node *HtmlNode) PropsToHTML() string {
@@ -86,13 +171,13 @@ func (node *HtmlNode) PropsToHTML() string {
 	}
 	slices.Sort(keys)
 
-	var result string
+	var result strings.Builder
 	for _, key := range keys {
 		val := node.Props[key]
-		result += fmt.Sprintf(" %s=\"%s\"", key, val)
+		fmt.Fprintf(&result, " %s=\"%s\"", key, EscapeHTML(val))
 	}
 
-	return result
+	return result.String()
 }
 
 func NewHtmlNode(tag, value string, children []HtmlNode, props map[string]string) HtmlNode {
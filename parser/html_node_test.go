@@ -17,3 +17,30 @@ func TestEscapeMD(t *testing.T) {
 		t.Fatalf("Escape character processing failed.\nExpected: %s\nResult: %s", expected, node.Value)
 	}
 }
+
+func TestHTMLEscaping(t *testing.T) {
+	tests := map[string]string{
+		"a < b & c":                           "<p>a &lt; b &amp; c</p>",
+		"&copy; &amp; &#35; &#x41; &bogus;":   "<p>© &amp; # A &amp;bogus;</p>",
+		"`<code> &amp;`":                      "<p><code>&lt;code&gt; &amp;amp;</code></p>",
+		"\\<b> is not a tag":                  "<p>&lt;b&gt; is not a tag</p>",
+		"<span class=\"x\">raw</span> html":   "<p><span class=\"x\">raw</span> html</p>",
+		"[link](http://a.b/\"onclick=\"x)":    "<p><a href=\"http://a.b/%22onclick=%22x\">link</a></p>",
+		"[link](/a b?c=1&amp;d=%20)":          "<p><a href=\"/a%20b?c=1&amp;d=%20\">link</a></p>",
+		"![a \"*quoted*\" alt](/img.png)":     "<p><img alt=\"a &quot;quoted&quot; alt\" src=\"/img.png\"></p>",
+		"```\n<script>alert(1)</script>\n```": "<pre>&lt;script&gt;alert(1)&lt;/script&gt;</pre>",
+	}
+
+	for input, expected := range tests {
+		t.Run(input, func(t *testing.T) {
+			result, err := MDtoHTML(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result.Body != expected {
+				t.Fatalf("Input:\n%s\nExpected:\n%s\nResult:\n%s", input, expected, result.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,498 @@
+package parser
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits the work done scanning for the end of deeply nested link labels and destinations
+const maxLinkNesting = 32
+
+// A run of emphasis characters (* or _) that may open and/or close emphasis
+// See: https://spec.commonmark.org/0.31.2/#delimiter-run
+type delimiterRun struct {
+	char              byte
+	count, origCount  int
+	canOpen, canClose bool
+
+	position   int // Offset of the run in the text, still comparable after removal from the stack
+	item       *inlineItem
+	prev, next *delimiterRun
+}
+
+// Either a finished inline node or a delimiter run waiting to be matched
+type inlineItem struct {
+	node       TextNode
+	delim      *delimiterRun
+	prev, next *inlineItem
+}
+
+// A doubly linked list of inline items with a stack of the delimiter runs among them
+type inlineList struct {
+	first, last     *inlineItem
+	delimiters      *delimiterRun // Top of the stack
+	delimiterBottom *delimiterRun
+}
+
+type openersBottomKey struct {
+	char      byte
+	canOpen   bool
+	countMod3 int
+}
+
+func isASCIIPunct(c byte) bool {
+	return c < utf8.RuneSelf && (unicode.IsPunct(rune(c)) || unicode.IsSymbol(rune(c)))
+}
+
+func isPunct(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+func runLength(text string, start int, char byte) int {
+	var n int
+	for start+n < len(text) && text[start+n] == char {
+		n++
+	}
+	return n
+}
+
+func (list *inlineList) push(item *inlineItem) {
+	item.prev = list.last
+	if list.last != nil {
+		list.last.next = item
+	} else {
+		list.first = item
+	}
+	list.last = item
+
+	if item.delim != nil {
+		item.delim.item = item
+		item.delim.prev = list.delimiters
+		if list.delimiters != nil {
+			list.delimiters.next = item.delim
+		} else {
+			list.delimiterBottom = item.delim
+		}
+		list.delimiters = item.delim
+	}
+}
+
+func (list *inlineList) remove(item *inlineItem) {
+	if item.prev != nil {
+		item.prev.next = item.next
+	} else {
+		list.first = item.next
+	}
+	if item.next != nil {
+		item.next.prev = item.prev
+	} else {
+		list.last = item.prev
+	}
+}
+
+func (list *inlineList) removeDelimiter(delim *delimiterRun) {
+	if delim.prev != nil {
+		delim.prev.next = delim.next
+	} else {
+		list.delimiterBottom = delim.next
+	}
+	if delim.next != nil {
+		delim.next.prev = delim.prev
+	} else {
+		list.delimiters = delim.prev
+	}
+}
+
+func newDelimiterRun(text string, start, count int) *delimiterRun {
+	// Beginning and end of text count as whitespace
+	before, after := ' ', ' '
+	if start > 0 {
+		before, _ = utf8.DecodeLastRuneInString(text[:start])
+	}
+	if start+count < len(text) {
+		after, _ = utf8.DecodeRuneInString(text[start+count:])
+	}
+
+	leftFlanking := !unicode.IsSpace(after) &&
+		(!isPunct(after) || unicode.IsSpace(before) || isPunct(before))
+	rightFlanking := !unicode.IsSpace(before) &&
+		(!isPunct(before) || unicode.IsSpace(after) || isPunct(after))
+
+	result := &delimiterRun{
+		char:      text[start],
+		count:     count,
+		origCount: count,
+		position:  start,
+	}
+
+	if result.char == '*' {
+		result.canOpen = leftFlanking
+		result.canClose = rightFlanking
+	} else {
+		// Underscores may not open or close emphasis inside of a word
+		result.canOpen = leftFlanking && (!rightFlanking || isPunct(before))
+		result.canClose = rightFlanking && (!leftFlanking || isPunct(after))
+	}
+
+	return result
+}
+
+// Returns the index of the closing backtick run matching an opening run of length n, or -1
+func findCodeSpanEnd(text string, start, n int) int {
+	for i := start; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+
+		run := runLength(text, i, '`')
+		if run == n {
+			return i
+		}
+		i += run
+	}
+
+	return -1
+}
+
+// See: https://spec.commonmark.org/0.31.2/#code-spans
+func normalizeCodeSpan(code string) string {
+	code = strings.ReplaceAll(code, "\n", " ")
+	if len(code) > 1 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+		code = code[1 : len(code)-1]
+	}
+	return code
+}
+
+// Returns the index of the bracket closing the one at start, or -1
+func findClosingBracket(text string, start int, open, close byte) int {
+	var depth int
+	for i := start; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case open:
+			depth++
+			if depth > maxLinkNesting {
+				return -1
+			}
+		case close:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// Scans a link of the form [label](url) starting at the opening bracket
+// Returns the label, the url and the index immediately after the closing parenthesis
+func scanLink(text string, start int) (string, string, int, bool) {
+	if start >= len(text) || text[start] != '[' {
+		return "", "", 0, false
+	}
+
+	labelEnd := findClosingBracket(text, start, '[', ']')
+	if labelEnd < 0 || labelEnd+1 >= len(text) || text[labelEnd+1] != '(' {
+		return "", "", 0, false
+	}
+
+	urlEnd := findClosingBracket(text, labelEnd+1, '(', ')')
+	if urlEnd < 0 {
+		return "", "", 0, false
+	}
+
+	label := text[start+1 : labelEnd]
+	url := strings.TrimSpace(text[labelEnd+2 : urlEnd])
+	return label, url, urlEnd + 1, true
+}
+
+// Reports a problem at offset in the text being parsed
+type inlineWarnFunc func(offset int, rule, message string)
+
+// Parse inline markdown (code spans, images, links and emphasis) into a tree of TextNodes
+func ParseInline(text string) TextNodeSlice {
+	return parseInline(text, nil)
+}
+
+func parseInline(text string, warn inlineWarnFunc) TextNodeSlice {
+	var list inlineList
+	var runs []*delimiterRun
+	var buf strings.Builder
+
+	// Lengths of backtick runs known to have no closing run later in the text
+	unmatchedCode := make(map[int]bool)
+
+	flush := func() {
+		if buf.Len() > 0 {
+			list.push(&inlineItem{
+				node: TextNode{TextType: textTypeText, Text: buf.String()},
+			})
+			buf.Reset()
+		}
+	}
+
+	for i := 0; i < len(text); {
+		c := text[i]
+
+		switch {
+		case c == '\\' && i+1 < len(text) && isASCIIPunct(text[i+1]):
+			buf.WriteByte(text[i+1])
+			i += 2
+
+		case c == '&':
+			entity := entityPattern.FindString(text[i:])
+			if entity == "" {
+				buf.WriteByte(c)
+				i++
+				break
+			}
+
+			buf.WriteString(html.UnescapeString(entity))
+			i += len(entity)
+
+		case c == '<':
+			n := scanInlineHTML(text[i:])
+			if n == 0 {
+				buf.WriteByte(c)
+				i++
+				break
+			}
+
+			flush()
+			list.push(&inlineItem{
+				node: TextNode{TextType: textTypeHTML, Text: text[i : i+n]},
+			})
+			i += n
+
+		case c == '`':
+			n := runLength(text, i, '`')
+			end := -1
+			if !unmatchedCode[n] {
+				end = findCodeSpanEnd(text, i+n, n)
+			}
+			if end < 0 {
+				unmatchedCode[n] = true
+				buf.WriteString(text[i : i+n])
+				i += n
+				break
+			}
+
+			flush()
+			list.push(&inlineItem{
+				node: TextNode{TextType: textTypeCode, Text: normalizeCodeSpan(text[i+n : end])},
+			})
+			i = end + n
+
+		case c == '!' && i+1 < len(text) && text[i+1] == '[':
+			label, url, end, ok := scanLink(text, i+1)
+			if !ok {
+				buf.WriteByte(c)
+				i++
+				break
+			}
+
+			flush()
+			list.push(&inlineItem{
+				node: TextNode{
+					TextType: textTypeImage,
+					Text:     ParseInline(label).plainText(),
+					URL:      normalizeURL(url),
+				},
+			})
+			i = end
+
+		case c == '[':
+			label, url, end, ok := scanLink(text, i)
+			if !ok {
+				buf.WriteByte(c)
+				i++
+				break
+			}
+
+			flush()
+			list.push(&inlineItem{
+				node: TextNode{
+					TextType: textTypeLink,
+					Text:     label,
+					URL:      normalizeURL(url),
+					Children: parseInline(label, warn.shift(i+1)),
+				},
+			})
+			i = end
+
+		case c == '*' || c == '_':
+			n := runLength(text, i, c)
+			flush()
+			runs = append(runs, newDelimiterRun(text, i, n))
+			list.push(&inlineItem{delim: runs[len(runs)-1]})
+			i += n
+
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+
+	list.processEmphasis()
+
+	if warn != nil {
+		// Only openers left over with a closer of the same character after them are reported,
+		// not literal delimiters such as 5*3 or the rest of a run such as ** in **a*
+		closers := make(map[byte]bool)
+		for i := len(runs) - 1; i >= 0; i-- {
+			delim := runs[i]
+			if delim.count == 0 {
+				continue
+			}
+			if delim.canOpen && closers[delim.char] {
+				warn(delim.position, RuleUnbalancedEmphasis,
+					fmt.Sprintf("unmatched emphasis delimiter %q", delim.literal().Text))
+			}
+			if delim.canClose {
+				closers[delim.char] = true
+			}
+		}
+	}
+
+	return collectTextNodes(list.first, nil)
+}
+
+// Offset warnings from a substring starting at start
+func (warn inlineWarnFunc) shift(start int) inlineWarnFunc {
+	if warn == nil {
+		return nil
+	}
+
+	return func(offset int, rule, message string) {
+		warn(start+offset, rule, message)
+	}
+}
+
+// Match delimiter runs into (possibly nested) emphasis nodes
+// See: https://spec.commonmark.org/0.31.2/#process-emphasis
+func (list *inlineList) processEmphasis() {
+	bottoms := make(map[openersBottomKey]int)
+
+	closer := list.delimiterBottom
+	for closer != nil {
+		if !closer.canClose {
+			closer = closer.next
+			continue
+		}
+
+		key := openersBottomKey{closer.char, closer.canOpen, closer.origCount % 3}
+		bottom, found := bottoms[key]
+		if !found {
+			bottom = -1
+		}
+
+		var opener *delimiterRun
+		for d := closer.prev; d != nil && d.position > bottom; d = d.prev {
+			if d.char != closer.char || !d.canOpen {
+				continue
+			}
+
+			// "Rule of 3"
+			if (d.canClose || closer.canOpen) &&
+				(d.origCount+closer.origCount)%3 == 0 &&
+				!(d.origCount%3 == 0 && closer.origCount%3 == 0) {
+				continue
+			}
+
+			opener = d
+			break
+		}
+
+		if opener == nil {
+			if closer.prev != nil {
+				bottoms[key] = closer.prev.position
+			}
+
+			next := closer.next
+			if !closer.canOpen {
+				list.removeDelimiter(closer)
+			}
+			closer = next
+			continue
+		}
+
+		used, textType := 1, textTypeItalic
+		if opener.count >= 2 && closer.count >= 2 {
+			used, textType = 2, textTypeBold
+		}
+		opener.count -= used
+		closer.count -= used
+
+		emphasis := &inlineItem{
+			node: TextNode{
+				TextType: textType,
+				Children: collectTextNodes(opener.item.next, closer.item),
+			},
+			prev: opener.item,
+			next: closer.item,
+		}
+		opener.item.next = emphasis
+		closer.item.prev = emphasis
+
+		// Delimiters between the opener and closer can no longer be matched
+		opener.next = closer
+		closer.prev = opener
+
+		if opener.count == 0 {
+			list.remove(opener.item)
+			list.removeDelimiter(opener)
+		}
+
+		if closer.count == 0 {
+			next := closer.next
+			list.remove(closer.item)
+			list.removeDelimiter(closer)
+			closer = next
+		}
+	}
+}
+
+func (delim *delimiterRun) literal() TextNode {
+	return TextNode{
+		TextType: textTypeText,
+		Text:     strings.Repeat(string(delim.char), delim.count),
+	}
+}
+
+// Collect items from first up to (but not including) last
+// Unmatched delimiters are converted to text and adjacent text nodes are merged
+func collectTextNodes(first, last *inlineItem) TextNodeSlice {
+	var result TextNodeSlice
+	var text strings.Builder
+
+	flush := func() {
+		if text.Len() > 0 {
+			result = append(result, TextNode{TextType: textTypeText, Text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for item := first; item != last && item != nil; item = item.next {
+		node := item.node
+		if item.delim != nil {
+			node = item.delim.literal()
+		}
+
+		if node.TextType == textTypeText {
+			text.WriteString(node.Text)
+		} else {
+			flush()
+			result = append(result, node)
+		}
+	}
+	flush()
+
+	return result
+}
@@ -1,30 +1,191 @@
 package parser
 
+import (
+	"bufio"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
 type InertParserResult struct {
-	Title string
-	Body  string
+	Title       string // Frontmatter title, the first top level heading or a name based on the file name
+	Description string // Frontmatter description or a summary of the first paragraph
+	Body        string
+	Metadata    Metadata  // Parsed frontmatter, empty if the document has none
+	Headings    []Heading // Headings in the body, in document order
+	Warnings    []Warning
+}
+
+type Heading struct {
+	Level int    // 1-6
+	ID    string // id attribute of the heading element
+	Text  string // Plain text of the heading
+}
+
+type InertParserOptions struct {
+	File        string         // Source file name used in warnings
+	Frontmatter bool           // Parse frontmatter at the start of the document into Metadata and leave it out of the body
+	Sanitize    SanitizePolicy // How raw html and link urls are sanitized
+
+	TableClasses    bool // Align table cells with align-* classes instead of inline styles
+	TableExtensions bool // Enable table captions, column spans and multi-line cells
+
+	// Leave the first h1 out of the body, for templates that already show the title
+	// Can be overridden per page with a stripTitle frontmatter value
+	StripTitle bool
 }
 
+// Maximum length of descriptions generated from the first paragraph
+const maxDescriptionLength = 160
+
 func MDtoHTML(src string) (InertParserResult, error) {
-	var result InertParserResult
+	return MDtoHTMLEx(src, InertParserOptions{})
+}
+
+func MDtoHTMLEx(src string, options InertParserOptions) (InertParserResult, error) {
+	var body strings.Builder
+
+	result, err := MDtoHTMLStreamEx(strings.NewReader(src), &body, options)
+	result.Body = body.String()
+
+	return result, err
+}
+
+// Read markdown from src and render it block by block to dest
+// The Body of the result is left empty
+func MDtoHTMLStream(src io.Reader, dest io.Writer) (InertParserResult, error) {
+	return MDtoHTMLStreamEx(src, dest, InertParserOptions{})
+}
+
+func MDtoHTMLStreamEx(src io.Reader, dest io.Writer, options InertParserOptions) (InertParserResult, error) {
+	result := InertParserResult{Metadata: make(Metadata)}
+
+	diag := &diagnostics{file: options.File}
+	out := bufio.NewWriter(dest)
+	blocks := NewBlockScanner(src)
+	blocks.diag = diag
+
+	if options.Frontmatter {
+		if frontmatter, format := blocks.ScanFrontmatter(); format != "" {
+			var err error
+			result.Metadata, err = parseFrontmatter(frontmatter, format, options.File)
+			if err != nil {
+				return result, err
+			}
+		}
+	}
+
+	stripTitle := options.StripTitle
+	if value, ok := result.Metadata["stripTitle"].(bool); ok {
+		stripTitle = value
+	}
+
+	var heading, paragraph string
+	var headingLevel int
+	for blocks.Scan() {
+		block, line := blocks.Block(), blocks.Line()
+		node := blockToHTMLNode(block, line, diag, options)
+
+		var warnings []blockWarning
+		node.processInnerText(func(offset int, rule, message string) {
+			warnings = append(warnings, blockWarning{offset, rule, message})
+		})
+		diag.warnInBlock(block, line, warnings)
+		node.sanitize(options.Sanitize)
+
+		if level := headingTagLevel(node.Tag); level > 0 {
+			text := strings.Join(strings.Fields(node.TextContent()), " ")
+
+			// Prefer the first heading of the highest level
+			if headingLevel == 0 || level < headingLevel {
+				heading, headingLevel = text, level
+				if level == 1 && stripTitle {
+					continue
+				}
+			}
 
-	blocks := ParseMDBlocks(src)
-	blockNodes, err := BlocksToHTMLNodes(blocks)
-	if err != nil {
+			result.Headings = append(result.Headings, Heading{Level: level, ID: node.Props["id"], Text: text})
+		}
+
+		if paragraph == "" && node.Tag == "p" {
+			paragraph = node.TextContent()
+		}
+
+		err := node.WriteHTML(out)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	result.Title = result.Metadata.GetString("title")
+	if result.Title == "" {
+		result.Title = heading
+	}
+	if result.Title == "" {
+		result.Title = titleFromFileName(options.File)
+	}
+
+	result.Description = result.Metadata.GetString("description")
+	if result.Description == "" {
+		result.Description = TruncateText(paragraph, maxDescriptionLength)
+	}
+
+	result.Warnings = diag.warnings
+
+	if err := blocks.Err(); err != nil {
 		return result, err
 	}
 
-	for i := range blockNodes {
-		blockNodes[i].ProcessInnerText()
-		blockNodes[i].UnescapeMD()
+	return result, out.Flush()
+}
+
+// Returns 1-6 for heading tags and 0 for anything else
+func headingTagLevel(tag string) int {
+	if len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6' {
+		return int(tag[1] - '0')
+	}
+	return 0
+}
+
+// Readable title from a file name, e.g. posts/apple-pie.md -> Apple pie
+// Index files are named after their directory
+func titleFromFileName(file string) string {
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	if name == "index" {
+		name = filepath.Base(filepath.Dir(file))
+	}
+	if file == "" || name == "." || name == string(filepath.Separator) {
+		return ""
+	}
+
+	name = strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return r == '-' || r == '_' || unicode.IsSpace(r)
+	}), " ")
+	if name == "" {
+		return ""
+	}
+
+	first, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(first)) + name[size:]
+}
+
+// Collapse whitespace in text and shorten it to at most max bytes at a word boundary
+func TruncateText(text string, max int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) <= max {
+		return text
 	}
 
-	for _, node := range blockNodes {
-		if result.Title == "" && node.Tag == "h1" {
-			result.Title = node.Value
+	const ellipsis = "…"
+	cut := strings.LastIndexByte(text[:max-len(ellipsis)+1], ' ')
+	if cut <= 0 {
+		cut = max - len(ellipsis)
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
 		}
-		result.Body += node.ToHTML()
 	}
 
-	return result, nil
+	return strings.TrimRight(text[:cut], " ,.;:") + ellipsis
 }
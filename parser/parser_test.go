@@ -0,0 +1,229 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+const testDocument = "# Heading\n\n" +
+	"A paragraph with **bold**, *italic* and `code` text.\n\n" +
+	"```go\nfunc main() {\n\n}\n```\n\n" +
+	"* List item 1\n* List item 2\n\n" +
+	"| Column 1 | Column 2 |\n| :--- | ---: |\n| Cell 1 | Cell 2 |\n\n"
+
+func TestMDtoHTMLStream(t *testing.T) {
+	expected, err := MDtoHTML(testDocument)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body strings.Builder
+	result, err := MDtoHTMLStream(strings.NewReader(testDocument), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Title != "Heading" {
+		t.Fatalf("Expected title: Heading\nResult: %s", result.Title)
+	}
+
+	if body.String() != expected.Body {
+		t.Fatalf("Streamed output does not match MDtoHTML()\nExpected:\n%s\nResult:\n%s", expected.Body, body.String())
+	}
+}
+
+// MB/s should remain roughly constant as input size increases
+func BenchmarkMDtoHTMLStream(b *testing.B) {
+	for _, size := range []int{1, 2, 4, 8} {
+		var md strings.Builder
+		for md.Len() < size<<20 {
+			md.WriteString(testDocument)
+		}
+		src := md.String()
+
+		b.Run(fmt.Sprintf("%dMB", size), func(b *testing.B) {
+			b.SetBytes(int64(len(src)))
+			for i := 0; i < b.N; i++ {
+				_, err := MDtoHTMLStream(strings.NewReader(src), io.Discard)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	const md = "---\ntitle: Frontmatter\n---\n" +
+		"# Heading\n\n" +
+		"Some *unbalanced _emphasis* here_\n\n" +
+		"| a | b |\n| --- | --- |\n| 1 | 2 | 3 |\n\n" +
+		"```\nunclosed code"
+
+	expected := []Warning{
+		{File: "test.md", Line: 6, Column: 18, Rule: RuleUnbalancedEmphasis},
+		{File: "test.md", Line: 10, Column: 1, Rule: RuleTableCellCount},
+		{File: "test.md", Line: 12, Column: 1, Rule: RuleUnclosedCodeFence},
+	}
+
+	result, err := MDtoHTMLEx(md, InertParserOptions{File: "test.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Warnings) != len(expected) {
+		t.Fatalf("Expected %d warnings, got %d:\n%v", len(expected), len(result.Warnings), result.Warnings)
+	}
+
+	for i, w := range result.Warnings {
+		e := expected[i]
+		if w.File != e.File || w.Line != e.Line || w.Column != e.Column || w.Rule != e.Rule {
+			t.Fatalf("Expected warning: %s\nResult: %s", e, w)
+		}
+	}
+}
+
+func TestSkipFrontmatter(t *testing.T) {
+	const md = "---\ntitle: Pie\n---\ntext"
+
+	result, err := MDtoHTMLEx(md, InertParserOptions{Frontmatter: true})
+	if err != nil || result.Body != "<p>text</p>" {
+		t.Fatalf("Expected frontmatter to be left out, got %q (%v)", result.Body, err)
+	}
+
+	result, err = MDtoHTML(md)
+	if err != nil || !strings.Contains(result.Body, "title: Pie") {
+		t.Fatalf("Expected frontmatter to be rendered without the option, got %q (%v)", result.Body, err)
+	}
+}
+
+func TestEmphasisWarnings(t *testing.T) {
+	tests := []struct {
+		md       string
+		expected string // Positions of the warnings
+	}{
+		{"price: 5*3", ""},
+		{"**bold* text", ""},
+		{"*a *a *a", ""},
+		{"a *b _c* d_\na *b _c* d_", "1:6 2:6"},
+		{"> quote\n> *b _c* d_", "2:6"},
+		{"- item\n- *b _c* d_", "2:6"},
+		{"Table: *b _c* d_\n| a | b |\n| - | - |\n| x | *b _c* d_ |", "1:11 4:10"},
+		{strings.Repeat("*a _b* c_ ", 12), "1:4 1:14 1:24 1:34 1:44 1:54 1:64 1:74 1:84 1:94 1:1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.md, func(t *testing.T) {
+			result, err := MDtoHTMLEx(test.md, InertParserOptions{TableExtensions: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var positions []string
+			for _, w := range result.Warnings {
+				positions = append(positions, fmt.Sprintf("%d:%d", w.Line, w.Column))
+			}
+			if strings.Join(positions, " ") != test.expected {
+				t.Fatalf("Expected warnings at: %s\nResult:\n%v", test.expected, result.Warnings)
+			}
+		})
+	}
+}
+
+func TestRawHTML(t *testing.T) {
+	tests := map[string]string{
+		"<div class=\"note\">\n**not bold**\n</div>\n\n**bold**": "<div class=\"note\">\n**not bold**\n</div><p><strong>bold</strong></p>",
+		"<style>\np { color: red; }\n\n</style>\n*after*":        "<style>\np { color: red; }\n\n</style><p><em>after</em></p>",
+		"text <span title=\"a*b*\">*em*</span> text":             "<p>text <span title=\"a*b*\"><em>em</em></span> text</p>",
+		"text <!-- *comment* --> text":                           "<p>text <!-- *comment* --> text</p>",
+		"a < b and c > d":                                        "<p>a &lt; b and c &gt; d</p>",
+	}
+
+	for input, expected := range tests {
+		t.Run(input, func(t *testing.T) {
+			result, err := MDtoHTML(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Body != expected {
+				t.Fatalf("Input:\n%s\nExpected:\n%s\nResult:\n%s", input, expected, result.Body)
+			}
+		})
+	}
+}
+
+func TestTitleAndDescription(t *testing.T) {
+	tests := []struct {
+		file, md           string
+		title, description string
+	}{
+		{"a.md", "---\ntitle: From *frontmatter*\ndescription: Described\n---\n# Heading\n\nText", "From *frontmatter*", "Described"},
+		{"a.md", "## Second\n\n# First **bold** `code`\n\nSome\ntext", "First bold code", "Some text"},
+		{"a.md", "## Only a level 2 heading", "Only a level 2 heading", ""},
+		{"posts/apple-pie_recipe.md", "No headings", "Apple pie recipe", "No headings"},
+		{"posts/apple-pie/index.md", "", "Apple pie", ""},
+		{"", "No headings or file", "", "No headings or file"},
+		{"a.md", strings.Repeat("word ", 100), "A", strings.TrimSpace(strings.Repeat("word ", 31)) + "…"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.md, func(t *testing.T) {
+			result, err := MDtoHTMLEx(test.md, InertParserOptions{File: test.file, Frontmatter: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Title != test.title {
+				t.Fatalf("Expected title: %q\nResult: %q", test.title, result.Title)
+			}
+			if result.Description != test.description {
+				t.Fatalf("Expected description: %q\nResult: %q", test.description, result.Description)
+			}
+		})
+	}
+}
+
+func TestStripTitle(t *testing.T) {
+	tests := []struct {
+		md    string
+		strip bool
+		body  string
+	}{
+		{"# Title\n\n# Second", true, `<h1 id="second">Second</h1>`},
+		{"# Title", false, `<h1 id="title">Title</h1>`},
+		{"---\nstripTitle: false\n---\n# Title", true, `<h1 id="title">Title</h1>`},
+		{"+++\nstripTitle = true\n+++\n# Title\n\ntext", false, `<p>text</p>`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.md, func(t *testing.T) {
+			result, err := MDtoHTMLEx(test.md, InertParserOptions{StripTitle: test.strip, Frontmatter: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Body != test.body {
+				t.Fatalf("Expected:\n%s\nResult:\n%s", test.body, result.Body)
+			}
+			if result.Title != "Title" {
+				t.Fatalf("Expected title to be kept when stripped, got %q", result.Title)
+			}
+		})
+	}
+}
+
+func TestHeadings(t *testing.T) {
+	result, err := MDtoHTMLEx("# Title\n\n## *First* part\n\ntext\n\n### Detail\n\n## Second", InertParserOptions{StripTitle: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Heading{
+		{Level: 2, ID: "first-part", Text: "First part"},
+		{Level: 3, ID: "detail", Text: "Detail"},
+		{Level: 2, ID: "second", Text: "Second"},
+	}
+	if fmt.Sprint(result.Headings) != fmt.Sprint(expected) {
+		t.Fatalf("Expected: %v\nResult: %v", expected, result.Headings)
+	}
+}
@@ -0,0 +1,216 @@
+package parser
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// See: https://spec.commonmark.org/0.31.2/#raw-html
+const (
+	htmlTagName     = `[A-Za-z][A-Za-z0-9-]*`
+	htmlAttrName    = `[A-Za-z_:][A-Za-z0-9_.:-]*`
+	htmlAttrValue   = `(?:[^"'=<>` + "`" + `\s]+|'[^']*'|"[^"]*")`
+	htmlAttribute   = `(?:\s+` + htmlAttrName + `(?:\s*=\s*` + htmlAttrValue + `)?)`
+	htmlOpenTag     = `<` + htmlTagName + htmlAttribute + `*\s*/?>`
+	htmlCloseTag    = `</` + htmlTagName + `\s*>`
+	htmlComment     = `<!-->|<!--->|<!--.*?-->`
+	htmlInstruction = `<\?.*?\?>`
+	htmlDeclaration = `<![A-Za-z][^>]*>`
+	htmlCDATA       = `<!\[CDATA\[.*?\]\]>`
+)
+
+var inlineHTMLPattern = regexp.MustCompile(`^(?s:` +
+	htmlOpenTag + `|` + htmlCloseTag + `|` + htmlComment + `|` +
+	htmlInstruction + `|` + htmlDeclaration + `|` + htmlCDATA + `)`,
+)
+
+// Returns the length of the raw html at the start of text, or 0
+func scanInlineHTML(text string) int {
+	match := inlineHTMLPattern.FindStringIndex(text)
+	if match == nil {
+		return 0
+	}
+	return match[1]
+}
+
+// Start and end conditions for each of the seven kinds of html block
+// See: https://spec.commonmark.org/0.31.2/#html-blocks
+var htmlBlockConditions = []struct {
+	start, end *regexp.Regexp
+}{
+	{
+		regexp.MustCompile(`(?i)^<(?:pre|script|style|textarea)(?:\s|>|$)`),
+		regexp.MustCompile(`(?i)</(?:pre|script|style|textarea)>`),
+	},
+	{regexp.MustCompile(`^<!--`), regexp.MustCompile(`-->`)},
+	{regexp.MustCompile(`^<\?`), regexp.MustCompile(`\?>`)},
+	{regexp.MustCompile(`^<![A-Za-z]`), regexp.MustCompile(`>`)},
+	{regexp.MustCompile(`^<!\[CDATA\[`), regexp.MustCompile(`\]\]>`)},
+	{
+		regexp.MustCompile(`(?i)^</?(?:address|article|aside|base|basefont|blockquote|body|` +
+			`caption|center|col|colgroup|dd|details|dialog|dir|div|dl|dt|fieldset|figcaption|` +
+			`figure|footer|form|frame|frameset|h1|h2|h3|h4|h5|h6|head|header|hr|html|iframe|` +
+			`legend|li|link|main|menu|menuitem|nav|noframes|ol|optgroup|option|p|param|search|` +
+			`section|summary|table|tbody|td|tfoot|th|thead|title|tr|track|ul)(?:\s|/?>|$)`),
+		nil, // Ends at a blank line
+	},
+	{
+		regexp.MustCompile(`^(?:` + htmlOpenTag + `|` + htmlCloseTag + `)\s*$`),
+		nil, // Ends at a blank line
+	},
+}
+
+// Condition 7 is the only kind of html block that can not interrupt a paragraph
+const htmlBlockParagraphCondition = 7
+
+// Returns the html block start condition (1-7) satisfied by line, or 0
+func htmlBlockStart(line string) int {
+	indent := len(line) - len(strings.TrimLeft(line, " "))
+	if indent > 3 {
+		return 0
+	}
+	line = line[indent:]
+
+	if !strings.HasPrefix(line, "<") {
+		return 0
+	}
+
+	for i, condition := range htmlBlockConditions {
+		if condition.start.MatchString(line) {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+// Whether line ends an html block with the given start condition
+// Blocks that end at a blank line are handled by the caller
+func htmlBlockEnds(condition int, line string) bool {
+	end := htmlBlockConditions[condition-1].end
+	return end != nil && end.MatchString(line)
+}
+
+type HTMLTokenType int
+
+const (
+	HTMLText HTMLTokenType = iota
+	HTMLStartTag
+	HTMLEndTag
+	HTMLSelfClosingTag
+	HTMLComment
+	HTMLDirective // Declarations, processing instructions and CDATA sections
+)
+
+type HTMLAttribute struct {
+	Name  string // Lowercase attribute name
+	Value string // Unquoted value with entities decoded
+}
+
+type HTMLToken struct {
+	Type   HTMLTokenType
+	Data   string // Lowercase tag name for tags, decoded text for text tokens
+	Attrs  []HTMLAttribute
+	Raw    string // Source text of the token
+	Offset int    // Byte offset of the token in the source
+}
+
+var (
+	htmlOpenTagPattern   = regexp.MustCompile(`^<(` + htmlTagName + `)(` + htmlAttribute + `*)\s*(/?)>`)
+	htmlCloseTagPattern  = regexp.MustCompile(`^</(` + htmlTagName + `)\s*>`)
+	htmlAttributePattern = regexp.MustCompile(`\s+(` + htmlAttrName + `)(?:\s*=\s*(` + htmlAttrValue + `))?`)
+	htmlCommentPattern   = regexp.MustCompile(`^(?s:` + htmlComment + `)`)
+	htmlDirectivePattern = regexp.MustCompile(`^(?s:` + htmlInstruction + `|` + htmlDeclaration + `|` + htmlCDATA + `)`)
+)
+
+// Elements whose content is never parsed as markup
+var htmlRawTextElements = map[string]bool{"script": true, "style": true}
+
+// Split src into tags, comments, directives and text
+// A '<' that does not start valid markup is treated as text
+func TokenizeHTML(src string) []HTMLToken {
+	var tokens []HTMLToken
+
+	textStart := 0
+	addText := func(end int, decode bool) {
+		if end <= textStart {
+			return
+		}
+		raw := src[textStart:end]
+		data := raw
+		if decode {
+			data = html.UnescapeString(raw)
+		}
+		tokens = append(tokens, HTMLToken{Type: HTMLText, Data: data, Raw: raw, Offset: textStart})
+	}
+
+	for i := 0; i < len(src); {
+		next := strings.IndexByte(src[i:], '<')
+		if next < 0 {
+			break
+		}
+		i += next
+
+		token, ok := scanHTMLToken(src[i:])
+		if !ok {
+			i++
+			continue
+		}
+
+		addText(i, true)
+		token.Offset = i
+		tokens = append(tokens, token)
+		i += len(token.Raw)
+		textStart = i
+
+		if token.Type == HTMLStartTag && htmlRawTextElements[token.Data] {
+			end := strings.Index(strings.ToLower(src[i:]), "</"+token.Data)
+			if end < 0 {
+				end = len(src) - i
+			}
+			i += end
+			addText(i, false)
+			textStart = i
+		}
+	}
+	addText(len(src), true)
+
+	return tokens
+}
+
+func scanHTMLToken(text string) (HTMLToken, bool) {
+	if match := htmlOpenTagPattern.FindStringSubmatch(text); match != nil {
+		token := HTMLToken{Type: HTMLStartTag, Data: strings.ToLower(match[1]), Raw: match[0]}
+		if match[3] == "/" {
+			token.Type = HTMLSelfClosingTag
+		}
+
+		for _, attr := range htmlAttributePattern.FindAllStringSubmatch(match[2], -1) {
+			value := attr[2]
+			if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+				value = value[1 : len(value)-1]
+			}
+			token.Attrs = append(token.Attrs, HTMLAttribute{
+				Name:  strings.ToLower(attr[1]),
+				Value: html.UnescapeString(value),
+			})
+		}
+
+		return token, true
+	}
+
+	if match := htmlCloseTagPattern.FindStringSubmatch(text); match != nil {
+		return HTMLToken{Type: HTMLEndTag, Data: strings.ToLower(match[1]), Raw: match[0]}, true
+	}
+
+	if match := htmlCommentPattern.FindString(text); match != "" {
+		return HTMLToken{Type: HTMLComment, Raw: match}, true
+	}
+
+	if match := htmlDirectivePattern.FindString(text); match != "" {
+		return HTMLToken{Type: HTMLDirective, Raw: match}, true
+	}
+
+	return HTMLToken{}, false
+}
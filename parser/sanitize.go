@@ -0,0 +1,173 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Controls how raw html and link urls from untrusted markdown are handled
+type SanitizePolicy int
+
+const (
+	SanitizeNone   SanitizePolicy = iota // Everything is passed through untouched
+	SanitizeUGC                          // Raw html is limited to an allowlist of tags and attributes
+	SanitizeStrict                       // Raw html is removed entirely
+)
+
+var sanitizePolicyNames = []string{"none", "ugc", "strict"}
+
+func (policy SanitizePolicy) String() string {
+	if policy < 0 || int(policy) >= len(sanitizePolicyNames) {
+		return fmt.Sprintf("SanitizePolicy(%d)", int(policy))
+	}
+	return sanitizePolicyNames[policy]
+}
+
+// Set the policy by name, so that it can be used as a flag.Value
+func (policy *SanitizePolicy) Set(name string) error {
+	index := slices.Index(sanitizePolicyNames, strings.ToLower(name))
+	if index < 0 {
+		return fmt.Errorf("unknown sanitize policy %q (expected %s)", name, strings.Join(sanitizePolicyNames, ", "))
+	}
+	*policy = SanitizePolicy(index)
+	return nil
+}
+
+var safeURLSchemes = []string{"http", "https", "mailto"}
+
+var urlAttributes = []string{"href", "src", "cite"}
+
+// Attributes allowed on every tag in the UGC policy
+var ugcGlobalAttributes = []string{"class", "dir", "lang", "title"}
+
+// Tags allowed in the UGC policy and their additional attributes
+var ugcTags = map[string][]string{
+	"a": {"href"}, "abbr": nil, "b": nil, "blockquote": {"cite"}, "br": nil,
+	"caption": nil, "cite": nil, "code": nil, "col": {"span"}, "colgroup": {"span"},
+	"dd": nil, "del": {"cite", "datetime"}, "details": {"open"}, "dfn": nil, "div": nil,
+	"dl": nil, "dt": nil, "em": nil, "figcaption": nil, "figure": nil,
+	"h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+	"hr": nil, "i": nil, "img": {"src", "alt", "width", "height"}, "ins": {"cite", "datetime"},
+	"kbd": nil, "li": {"value"}, "mark": nil, "ol": {"start", "reversed"}, "p": nil,
+	"pre": nil, "q": {"cite"}, "rp": nil, "rt": nil, "ruby": nil, "s": nil, "samp": nil,
+	"small": nil, "span": nil, "strong": nil, "sub": nil, "summary": nil, "sup": nil,
+	"table": nil, "tbody": nil, "td": {"colspan", "rowspan", "align"}, "tfoot": nil,
+	"th": {"colspan", "rowspan", "align", "scope"}, "thead": nil, "time": {"datetime"},
+	"tr": nil, "u": nil, "ul": nil, "var": nil,
+}
+
+// Tags that are removed along with everything inside of them in the UGC policy
+var ugcDroppedContentTags = []string{
+	"script", "style", "iframe", "object", "embed", "noscript",
+	"template", "textarea", "title", "svg", "math",
+}
+
+// Whether url is relative or uses one of the safe schemes
+func isSafeURL(url string) bool {
+	// Browsers ignore control characters and whitespace when reading the scheme
+	url = strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, url)
+
+	colon := strings.IndexByte(url, ':')
+	if colon < 0 || strings.ContainsAny(url[:colon], "/?#") {
+		return true
+	}
+
+	return slices.Contains(safeURLSchemes, strings.ToLower(url[:colon]))
+}
+
+// Rewrite raw html according to policy
+func SanitizeHTML(src string, policy SanitizePolicy) string {
+	switch policy {
+	case SanitizeNone:
+		return src
+	case SanitizeStrict:
+		return ""
+	}
+
+	var result strings.Builder
+	var skipTag string
+	var skipDepth int
+
+	for _, token := range TokenizeHTML(src) {
+		if skipTag != "" {
+			if token.Data == skipTag && token.Type == HTMLStartTag {
+				skipDepth++
+			} else if token.Data == skipTag && token.Type == HTMLEndTag {
+				skipDepth--
+				if skipDepth == 0 {
+					skipTag = ""
+				}
+			}
+			continue
+		}
+
+		switch token.Type {
+		case HTMLText:
+			result.WriteString(EscapeHTML(token.Data))
+
+		case HTMLStartTag, HTMLSelfClosingTag:
+			if slices.Contains(ugcDroppedContentTags, token.Data) {
+				if token.Type == HTMLStartTag {
+					skipTag, skipDepth = token.Data, 1
+				}
+				continue
+			}
+
+			tagAttributes, ok := ugcTags[token.Data]
+			if !ok {
+				continue
+			}
+
+			result.WriteString("<" + token.Data)
+			for _, attr := range token.Attrs {
+				if !slices.Contains(ugcGlobalAttributes, attr.Name) && !slices.Contains(tagAttributes, attr.Name) {
+					continue
+				}
+				if slices.Contains(urlAttributes, attr.Name) && !isSafeURL(attr.Value) {
+					continue
+				}
+				fmt.Fprintf(&result, ` %s="%s"`, attr.Name, EscapeHTML(attr.Value))
+			}
+			if token.Type == HTMLSelfClosingTag {
+				result.WriteString(" /")
+			}
+			result.WriteString(">")
+
+		case HTMLEndTag:
+			if _, ok := ugcTags[token.Data]; ok {
+				result.WriteString("</" + token.Data + ">")
+			}
+
+			// Comments and directives are dropped
+		}
+	}
+
+	return result.String()
+}
+
+// Apply policy to raw html and url props of node and its children
+func (node *HtmlNode) sanitize(policy SanitizePolicy) {
+	if policy == SanitizeNone {
+		return
+	}
+
+	if node.Raw {
+		node.Value = SanitizeHTML(node.Value, policy)
+	}
+
+	for name, value := range node.Props {
+		if slices.Contains(urlAttributes, name) && !isSafeURL(value) {
+			delete(node.Props, name)
+		}
+	}
+
+	for i := range node.Children {
+		node.Children[i].sanitize(policy)
+	}
+}
@@ -0,0 +1,85 @@
+package parser
+
+import "testing"
+
+func TestTokenizeHTML(t *testing.T) {
+	const src = `<p class='a &amp; b'>x < y</p><!-- c --><script>if (a<b) {}</script><br/>`
+	expected := []HTMLToken{
+		{Type: HTMLStartTag, Data: "p", Attrs: []HTMLAttribute{{Name: "class", Value: "a & b"}}},
+		{Type: HTMLText, Data: "x < y"},
+		{Type: HTMLEndTag, Data: "p"},
+		{Type: HTMLComment},
+		{Type: HTMLStartTag, Data: "script"},
+		{Type: HTMLText, Data: "if (a<b) {}"},
+		{Type: HTMLEndTag, Data: "script"},
+		{Type: HTMLSelfClosingTag, Data: "br"},
+	}
+
+	result := TokenizeHTML(src)
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d:\n%#v", len(expected), len(result), result)
+	}
+
+	for i, token := range result {
+		e := expected[i]
+		if token.Type != e.Type || (e.Data != "" && token.Data != e.Data) || len(token.Attrs) != len(e.Attrs) {
+			t.Fatalf("Expected token:\n%#v\nResult:\n%#v", e, token)
+		}
+		for j := range e.Attrs {
+			if token.Attrs[j] != e.Attrs[j] {
+				t.Fatalf("Expected attribute:\n%#v\nResult:\n%#v", e.Attrs[j], token.Attrs[j])
+			}
+		}
+		if src[token.Offset:token.Offset+len(token.Raw)] != token.Raw {
+			t.Fatalf("Incorrect offset %d for token %q", token.Offset, token.Raw)
+		}
+	}
+}
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := map[string]string{
+		`<div class="recipe">ok</div>`:                 `<div class="recipe">ok</div>`,
+		`<img src="x.png" onerror="alert(1)">`:         `<img src="x.png">`,
+		`<a href="javascript:alert(1)">link</a>`:       `<a>link</a>`,
+		`<a href="JaVa&#x09;Script:alert(1)">link</a>`: `<a>link</a>`,
+		`<a href="https://example.com">link</a>`:       `<a href="https://example.com">link</a>`,
+		`<script>alert("<b>hi</b>")</script>after`:     `after`,
+		`<custom-tag>text</custom-tag>`:                `text`,
+		`<!-- comment -->`:                             ``,
+		`<p title='"quoted"'>`:                         `<p title="&quot;quoted&quot;">`,
+	}
+
+	for input, expected := range tests {
+		t.Run(input, func(t *testing.T) {
+			result := SanitizeHTML(input, SanitizeUGC)
+			if result != expected {
+				t.Fatalf("Input:\n%s\nExpected:\n%s\nResult:\n%s", input, expected, result)
+			}
+		})
+	}
+}
+
+func TestSanitizePolicies(t *testing.T) {
+	const md = "<div onclick=\"steal()\">\nhi\n</div>\n\n" +
+		"[link](javascript:alert(1)) ![img](data:text/html,x) [ok](/recipes/)"
+
+	expected := map[SanitizePolicy]string{
+		SanitizeNone: "<div onclick=\"steal()\">\nhi\n</div>" +
+			"<p><a href=\"javascript:alert(1)\">link</a> <img alt=\"img\" src=\"data:text/html,x\"> <a href=\"/recipes/\">ok</a></p>",
+		SanitizeUGC: "<div>\nhi\n</div>" +
+			"<p><a>link</a> <img alt=\"img\"> <a href=\"/recipes/\">ok</a></p>",
+		SanitizeStrict: "<p><a>link</a> <img alt=\"img\"> <a href=\"/recipes/\">ok</a></p>",
+	}
+
+	for policy, body := range expected {
+		t.Run(policy.String(), func(t *testing.T) {
+			result, err := MDtoHTMLEx(md, InertParserOptions{Sanitize: policy})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Body != body {
+				t.Fatalf("Expected:\n%s\nResult:\n%s", body, result.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,338 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Caption line placed directly before or after a table when table extensions are enabled
+const tableCaptionPrefix = "Table: "
+
+type tableCell struct {
+	text   string // Lines of multi-line cells are separated by \n
+	span   int
+	source []int // Offset in the table block of each line of text, nil if it is not known
+}
+
+type table struct {
+	caption    string
+	captionAt  int // Offset of the caption in the table block
+	alignments []string
+	header     []tableCell
+	rows       [][]tableCell
+	sortable   bool // Mark the table and its header cells for client-side sorting
+}
+
+// Split a table row into cells without trimming them
+// Escaped pipes and pipes inside code spans do not separate cells
+func splitTableCells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+
+	var cells []string
+	var cell strings.Builder
+	var split bool
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		split = false
+
+		switch {
+		case c == '\\' && i+1 < len(line):
+			if line[i+1] != '|' {
+				cell.WriteByte(c)
+			}
+			cell.WriteByte(line[i+1])
+			i++
+		case c == '`':
+			run := runLength(line, i, '`')
+			end := findCodeSpanEnd(line, i+run, run)
+			if end < 0 {
+				end = i
+			}
+			cell.WriteString(strings.ReplaceAll(line[i:end+run], "\\|", "|"))
+			i = end + run - 1
+		case c == '|':
+			cells = append(cells, cell.String())
+			cell.Reset()
+			split = true
+		default:
+			cell.WriteByte(c)
+		}
+	}
+
+	// A trailing pipe closes the last cell instead of starting a new one
+	if !split || len(cells) == 0 {
+		cells = append(cells, cell.String())
+	}
+
+	return cells
+}
+
+// Split a table row into trimmed cells
+func splitTableRow(line string) []string {
+	cells := splitTableCells(line)
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+// Parse the column alignments of a table delimiter row, e.g. | :--- | :-: | ---: |
+func parseTableDelimiterRow(line string) ([]string, bool) {
+	if !strings.Contains(line, "|") {
+		return nil, false
+	}
+
+	cells := splitTableRow(line)
+	alignments := make([]string, len(cells))
+	for i, cell := range cells {
+		left := strings.HasPrefix(cell, ":")
+		right := strings.HasSuffix(cell, ":")
+
+		dashes := strings.TrimSuffix(strings.TrimPrefix(cell, ":"), ":")
+		if dashes == "" || strings.Trim(dashes, "-") != "" {
+			return nil, false
+		}
+
+		if left && right {
+			alignments[i] = "center"
+		} else if right {
+			alignments[i] = "right"
+		} else {
+			alignments[i] = "left"
+		}
+	}
+
+	return alignments, true
+}
+
+// Returns the column alignments of a table block
+// A table is a header row followed by a delimiter row with the same number of cells
+func checkTable(block string) ([]string, bool) {
+	header, rest, found := strings.Cut(block, "\n")
+	if !found {
+		return nil, false
+	}
+	delimiter, _, _ := strings.Cut(rest, "\n")
+
+	alignments, valid := parseTableDelimiterRow(delimiter)
+	if !valid || len(splitTableRow(header)) != len(alignments) {
+		return nil, false
+	}
+
+	return alignments, true
+}
+
+// Remove a caption line from the start or end of a table block
+// Returns the number of lines removed from the start of the block
+func cutTableCaption(block string) (string, string, int) {
+	if first, rest, found := strings.Cut(block, "\n"); found && strings.HasPrefix(first, tableCaptionPrefix) {
+		return rest, strings.TrimSpace(first[len(tableCaptionPrefix):]), 1
+	}
+
+	if i := strings.LastIndexByte(block, '\n'); i >= 0 && strings.HasPrefix(block[i+1:], tableCaptionPrefix) {
+		return block[:i], strings.TrimSpace(block[i+1+len(tableCaptionPrefix):]), 0
+	}
+
+	return block, "", 0
+}
+
+// With extensions, an empty cell between two adjacent pipes extends the span of the cell before it
+// start: Offset of line in the table block
+func parseTableCells(line string, start int, extensions bool) []tableCell {
+	var cells []tableCell
+	var end int // End of the last cell found in line
+	for _, cell := range splitTableCells(line) {
+		if extensions && cell == "" && len(cells) > 0 {
+			cells[len(cells)-1].span++
+			continue
+		}
+
+		text := strings.TrimSpace(cell)
+		result := tableCell{text: text, span: 1}
+		// Cells with escaped pipes are not found, and are reported at the start of the table
+		if i := strings.Index(line[end:], text); i >= 0 {
+			result.source = []int{start + end + i}
+			end += i + len(text)
+		}
+		cells = append(cells, result)
+	}
+	return cells
+}
+
+// Pad or truncate cells to fill exactly the given number of columns
+// Returns the fitted cells and the number of columns originally spanned
+func fitTableRow(cells []tableCell, columns int) ([]tableCell, int) {
+	var total, used int
+	var fitted []tableCell
+
+	for _, cell := range cells {
+		total += cell.span
+		if used < columns {
+			cell.span = min(cell.span, columns-used)
+			fitted = append(fitted, cell)
+			used += cell.span
+		}
+	}
+
+	for ; used < columns; used++ {
+		fitted = append(fitted, tableCell{span: 1})
+	}
+
+	return fitted, total
+}
+
+// Parse a table block starting at line blockLine, reporting rows with the wrong number of cells to diag
+// With extensions, a row ending in \ continues onto the next line
+func parseTable(block string, blockLine int, diag *diagnostics, extensions bool) table {
+	var t table
+
+	var start int // Offset of the rows in the block, after a caption line
+	if extensions {
+		var rows string
+		var offset int
+		rows, t.caption, offset = cutTableCaption(block)
+		if offset > 0 {
+			start = len(block) - len(rows)
+			t.captionAt = strings.Index(block, t.caption)
+		} else {
+			t.captionAt = strings.LastIndex(block, t.caption)
+		}
+		block, blockLine = rows, blockLine+offset
+	}
+
+	lines := strings.Split(block, "\n")
+	source := lineOffsets(block, start)
+	t.alignments, _ = checkTable(block)
+	t.header, _ = fitTableRow(parseTableCells(lines[0], source[0], extensions), len(t.alignments))
+
+	var row []tableCell
+	var rowLine int
+	for i := 2; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		continued := extensions && strings.HasSuffix(line, "\\") && !strings.HasSuffix(line, "\\\\")
+		if continued {
+			line = line[:len(line)-1]
+		}
+
+		cells := parseTableCells(line, source[i]+len(lines[i])-len(strings.TrimLeft(lines[i], " \t")), extensions)
+		if row == nil {
+			row, rowLine = cells, i
+		} else {
+			for j, cell := range cells {
+				if j >= len(row) {
+					row = append(row, cell)
+				} else if cell.text != "" {
+					row[j].text += "\n" + cell.text
+					if row[j].source != nil && cell.source != nil {
+						row[j].source = append(row[j].source, cell.source...)
+					} else {
+						row[j].source = nil
+					}
+				}
+			}
+		}
+
+		if continued && i+1 < len(lines) {
+			continue
+		}
+
+		fitted, columns := fitTableRow(row, len(t.alignments))
+		if columns > len(t.alignments) {
+			diag.warn(blockLine+rowLine, 1, RuleTableCellCount,
+				"table row has %d cells, expected %d; extra cells are dropped", columns, len(t.alignments))
+		} else if columns < len(t.alignments) {
+			diag.warn(blockLine+rowLine, 1, RuleTableCellCount,
+				"table row has %d cells, expected %d; missing cells are left empty", columns, len(t.alignments))
+		}
+
+		t.rows = append(t.rows, fitted)
+		row = nil
+	}
+
+	return t
+}
+
+// Render the table inside a scrollable wrapper div
+// alignClasses replaces inline styles with classes for use with a Content-Security-Policy
+func (t *table) toHTMLNode(alignClasses bool) HtmlNode {
+	wrapper := HtmlNode{
+		Tag:   "div",
+		Props: map[string]string{"style": "overflow-x:auto;"},
+	}
+	if alignClasses {
+		wrapper.Props = map[string]string{"class": "table-wrapper"}
+	}
+
+	node := HtmlNode{Tag: "table"}
+	if t.sortable {
+		node.Props = map[string]string{"class": "sortable"}
+	}
+	if t.caption != "" {
+		node.Children = append(node.Children, HtmlNode{Tag: "caption", Value: t.caption, source: []int{t.captionAt}})
+	}
+
+	if t.header != nil {
+		head := HtmlNode{Tag: "thead"}
+		head.Children = append(head.Children, t.rowToHTMLNode(t.header, "th", alignClasses))
+		node.Children = append(node.Children, head)
+	}
+
+	body := HtmlNode{Tag: "tbody"}
+	for _, row := range t.rows {
+		body.Children = append(body.Children, t.rowToHTMLNode(row, "td", alignClasses))
+	}
+	node.Children = append(node.Children, body)
+	wrapper.Children = append(wrapper.Children, node)
+
+	return wrapper
+}
+
+func (t *table) rowToHTMLNode(cells []tableCell, tag string, alignClasses bool) HtmlNode {
+	row := HtmlNode{Tag: "tr"}
+
+	var column int
+	for _, cell := range cells {
+		alignment := "left"
+		if column < len(t.alignments) {
+			alignment = t.alignments[column]
+		}
+		column += cell.span
+
+		node := HtmlNode{
+			Tag:   tag,
+			Props: map[string]string{"style": "text-align: " + alignment},
+		}
+		if alignClasses {
+			node.Props = map[string]string{"class": "align-" + alignment}
+		}
+		if t.sortable && tag == "th" {
+			node.Props["class"] = strings.TrimSpace(node.Props["class"] + " sortable-column")
+		}
+		if cell.span > 1 {
+			node.Props["colspan"] = strconv.Itoa(cell.span)
+		}
+
+		lines := strings.Split(cell.text, "\n")
+		if len(lines) == 1 {
+			node.Value = cell.text
+			node.source = cell.source
+		} else {
+			for i, line := range lines {
+				if i > 0 {
+					node.Children = append(node.Children, HtmlNode{Tag: "br"})
+				}
+
+				child := HtmlNode{Value: line}
+				if len(cell.source) == len(lines) {
+					child.source = cell.source[i : i+1]
+				}
+				node.Children = append(node.Children, child)
+			}
+		}
+
+		row.Children = append(row.Children, node)
+	}
+
+	return row
+}
@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitTableRow(t *testing.T) {
+	tests := map[string][]string{
+		"| a | b |":          {"a", "b"},
+		"a | b":              {"a", "b"},
+		"| a | b":            {"a", "b"},
+		"| a \\| b | c |":    {"a | b", "c"},
+		"| `a|b` | c |":      {"`a|b`", "c"},
+		"| `a\\|b` | c |":    {"`a|b`", "c"},
+		"| ``a|`b`` | c |":   {"``a|`b``", "c"},
+		"| `unclosed | c |":  {"`unclosed", "c"},
+		"| \\*a\\* |  |":     {"\\*a\\*", ""},
+		"|":                  {""},
+		"| a | b | trailing": {"a", "b", "trailing"},
+	}
+
+	for input, expected := range tests {
+		t.Run(input, func(t *testing.T) {
+			result := splitTableRow(input)
+			if strings.Join(result, "\x00") != strings.Join(expected, "\x00") {
+				t.Fatalf("Input:\n%s\nExpected:\n%q\nResult:\n%q", input, expected, result)
+			}
+		})
+	}
+}
+
+func TestTables(t *testing.T) {
+	tests := map[string]string{
+		"| **a** | b |\n| - | :-: |\n| 1 | 2 |": "<thead><tr>" +
+			`<th style="text-align: left"><strong>a</strong></th><th style="text-align: center">b</th></tr></thead>` +
+			`<tbody><tr><td style="text-align: left">1</td><td style="text-align: center">2</td></tr></tbody>`,
+		"a | b\n--- | ---:\n1 | `x|y` | extra\n2": "<thead><tr>" +
+			`<th style="text-align: left">a</th><th style="text-align: right">b</th></tr></thead><tbody>` +
+			`<tr><td style="text-align: left">1</td><td style="text-align: right"><code>x|y</code></td></tr>` +
+			`<tr><td style="text-align: left">2</td><td style="text-align: right"></td></tr></tbody>`,
+	}
+
+	for input, expected := range tests {
+		t.Run(input, func(t *testing.T) {
+			if GetBlockType(input) != blockTypeTable {
+				t.Fatalf("Input:\n%s\nExpected a table block", input)
+			}
+
+			result, err := MDtoHTML(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected = `<div style="overflow-x:auto;"><table>` + expected + "</table></div>"
+			if result.Body != expected {
+				t.Fatalf("Input:\n%s\nExpected:\n%s\nResult:\n%s", input, expected, result.Body)
+			}
+		})
+	}
+
+	for _, input := range []string{"| a | b |\n| --- |", "a | b\n---", "| a |\n| -x- |"} {
+		if GetBlockType(input) == blockTypeTable {
+			t.Fatalf("Input:\n%s\nExpected a paragraph", input)
+		}
+	}
+}
+
+func TestTableExtensions(t *testing.T) {
+	const md = "Table: *Units* of measure\n" +
+		"| Name | Symbol | Notes |\n| :-- | :-: | --: |\n" +
+		"| Metre | m | base \\\n| | | unit |\n" +
+		"| Spanning two columns || x |\n| short |"
+
+	const expected = `<div class="table-wrapper"><table><caption><em>Units</em> of measure</caption>` +
+		`<thead><tr><th class="align-left">Name</th><th class="align-center">Symbol</th><th class="align-right">Notes</th></tr></thead><tbody>` +
+		`<tr><td class="align-left">Metre</td><td class="align-center">m</td><td class="align-right">base<br>unit</td></tr>` +
+		`<tr><td class="align-left" colspan="2">Spanning two columns</td><td class="align-right">x</td></tr>` +
+		`<tr><td class="align-left">short</td><td class="align-center"></td><td class="align-right"></td></tr>` +
+		`</tbody></table></div>`
+
+	result, err := MDtoHTMLEx(md, InertParserOptions{TableClasses: true, TableExtensions: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Body != expected {
+		t.Fatalf("Expected:\n%s\nResult:\n%s", expected, result.Body)
+	}
+
+	if len(result.Warnings) != 1 || result.Warnings[0].Line != 7 || result.Warnings[0].Rule != RuleTableCellCount {
+		t.Fatalf("Expected a single %s warning on line 7, got:\n%v", RuleTableCellCount, result.Warnings)
+	}
+}
@@ -14,14 +14,11 @@ func TestDelimSplit(t *testing.T) {
 	var result TextNodeSlice
 	result = append(result, node)
 
-	result, _ = result.Split("`", textTypeCode)
-	if len(result) != 3 || result[1].TextType != textTypeCode {
-		t.Fatalf("Incorrect Split() output for delimiter %s: %s", "`", fmt.Sprintf("%#v", result))
-	}
-
-	result, _ = result.Split("**", textTypeBold)
-	if len(result) != 4 || result[3].TextType != textTypeBold {
-		t.Fatalf("Incorrect Split() output for delimiter %s: %s", "**\n\n", fmt.Sprintf("%#v", result))
+	result, _ = result.SplitAll()
+	if len(result) != 4 ||
+		result[1].TextType != textTypeCode ||
+		result[3].TextType != textTypeBold {
+		t.Fatalf("Incorrect SplitAll() output for delimiters ` and **: %s", fmt.Sprintf("%#v", result))
 	}
 }
 
@@ -31,10 +28,9 @@ func TestImgSplit(t *testing.T) {
 		Text: `Before image1 ![img1 alt text](http://img1.url) after img1,
 		before img2 ![img2 alt text](http://img2.url)`,
 	}
-	var result TextNodeSlice
-	result, _ = node.SplitImageNodes()
+	result := ParseInline(node.Text)
 	if len(result) != 4 || result[1].TextType != textTypeImage || result[3].TextType != textTypeImage {
-		t.Fatalf("Incorrect output for SplitImageNodes():\nInput:\n%s\nResult:\n%s", node.Text, result.ToString())
+		t.Fatalf("Incorrect output for ParseInline():\nInput:\n%s\nResult:\n%s", node.Text, result.ToString())
 	}
 }
 
@@ -44,10 +40,9 @@ func TestLinkSplit(t *testing.T) {
 		Text: `Before link1 [link1 anchor text](http://link1.url) after link1,
 		before link2 [link2 anchor text](http://link2.url)`,
 	}
-	var result TextNodeSlice
-	result, _ = node.SplitLinkNodes()
+	result := ParseInline(node.Text)
 	if len(result) != 4 || result[1].TextType != textTypeLink || result[3].TextType != textTypeLink {
-		t.Fatalf("Incorrect output for SplitLinkNodes():\nInput:\n%s\nResult:\n%s", node.Text, result.ToString())
+		t.Fatalf("Incorrect output for ParseInline():\nInput:\n%s\nResult:\n%s", node.Text, result.ToString())
 	}
 }
 
@@ -69,3 +64,37 @@ func TestSplitAll(t *testing.T) {
 		t.Fatalf("Incorrect output for SplitAll():\nInput:\n%s\nOutput:\n%s", nText, nodes.ToString())
 	}
 }
+
+func TestEmphasis(t *testing.T) {
+	tests := map[string]string{
+		"*italic*":                       "<em>italic</em>",
+		"**bold**":                       "<strong>bold</strong>",
+		"**bold *and italic***":          "<strong>bold <em>and italic</em></strong>",
+		"***both***":                     "<em><strong>both</strong></em>",
+		"*italic **and bold***":          "<em>italic <strong>and bold</strong></em>",
+		"snake_case_names":               "snake_case_names",
+		"an unmatched * star":            "an unmatched * star",
+		"*unclosed":                      "*unclosed",
+		"**unbalanced*":                  "*<em>unbalanced</em>",
+		"foo*bar*baz":                    "foo<em>bar</em>baz",
+		"_foo_bar":                       "_foo_bar",
+		"`*not emphasis*`":               "<code>*not emphasis*</code>",
+		"`` a ` b ``":                    "<code>a ` b</code>",
+		"[*emphasis* in link](http://a)": "<a href=\"http://a\"><em>emphasis</em> in link</a>",
+		"*[link](http://a) in emphasis*": "<em><a href=\"http://a\">link</a> in emphasis</em>",
+		"\\*escaped*":                    "*escaped*",
+	}
+
+	for input, expected := range tests {
+		t.Run(input, func(t *testing.T) {
+			node := HtmlNode{Tag: "p", Value: input}
+			node.ProcessInnerText()
+
+			result := node.ToHTML()
+			expected = "<p>" + expected + "</p>"
+			if result != expected {
+				t.Fatalf("Input:\n%s\nExpected:\n%s\nResult:\n%s", input, expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,517 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// TOML 1.0 as used in frontmatter and config files.
+// Local times are kept as strings. Unlike the spec, tables created by dotted keys
+// or inline tables may be extended later in the document.
+// See: https://toml.io/en/v1.0.0
+
+const (
+	tomlBareKeyChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_-"
+	tomlValueEnd     = " \t\n#,]}" // Characters that end an unquoted value
+)
+
+var (
+	tomlIntPattern   = regexp.MustCompile(`^[-+]?(?:0|[1-9](?:_?[0-9])*)$`)
+	tomlFloatPattern = regexp.MustCompile(`^[-+]?(?:0|[1-9](?:_?[0-9])*)(?:\.[0-9](?:_?[0-9])*)?(?:[eE][-+]?[0-9](?:_?[0-9])*)?$`)
+	tomlDatePattern  = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}$`)
+	tomlTimePattern  = regexp.MustCompile(`^[0-9]{2}:[0-9]{2}:[0-9]{2}(?:\.[0-9]+)?$`)
+)
+
+var tomlTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+type tomlParser struct {
+	src     string
+	pos     int
+	defined map[string]bool // Tables defined with a [header]
+}
+
+// Parse a TOML document
+// Values are string, int, float64, bool, time.Time, []any or map[string]any
+func ParseTOML(src string) (Metadata, error) {
+	p := tomlParser{src: strings.ReplaceAll(src, "\r\n", "\n"), defined: make(map[string]bool)}
+	root := make(map[string]any)
+	table := root
+
+	for {
+		p.skipBlankLines()
+		if p.pos >= len(p.src) {
+			break
+		}
+
+		var err error
+		if p.src[p.pos] == '[' {
+			table, err = p.parseTableHeader(root)
+		} else {
+			err = p.parseKeyValue(table)
+		}
+		if err != nil {
+			return make(Metadata), err
+		}
+
+		if err := p.expectLineEnd(); err != nil {
+			return make(Metadata), err
+		}
+	}
+
+	return Metadata(root), nil
+}
+
+func (p *tomlParser) errorf(format string, a ...any) error {
+	return &SyntaxError{
+		Line:    strings.Count(p.src[:min(p.pos, len(p.src))], "\n") + 1,
+		Message: fmt.Sprintf(format, a...),
+	}
+}
+
+func (p *tomlParser) skipWhitespace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *tomlParser) skipComment() {
+	if p.pos < len(p.src) && p.src[p.pos] == '#' {
+		end := strings.IndexByte(p.src[p.pos:], '\n')
+		if end < 0 {
+			end = len(p.src) - p.pos
+		}
+		p.pos += end
+	}
+}
+
+// Skip whitespace, comments and newlines
+func (p *tomlParser) skipBlankLines() {
+	for {
+		p.skipWhitespace()
+		p.skipComment()
+		if p.pos >= len(p.src) || p.src[p.pos] != '\n' {
+			return
+		}
+		p.pos++
+	}
+}
+
+func (p *tomlParser) expectLineEnd() error {
+	p.skipWhitespace()
+	p.skipComment()
+	if p.pos < len(p.src) && p.src[p.pos] != '\n' {
+		return p.errorf("expected the end of the line, found %q", p.src[p.pos:p.lineEnd()])
+	}
+	return nil
+}
+
+func (p *tomlParser) lineEnd() int {
+	end := strings.IndexByte(p.src[p.pos:], '\n')
+	if end < 0 {
+		return len(p.src)
+	}
+	return p.pos + end
+}
+
+func (p *tomlParser) consume(prefix string) bool {
+	if strings.HasPrefix(p.src[p.pos:], prefix) {
+		p.pos += len(prefix)
+		return true
+	}
+	return false
+}
+
+// Parse a [table] or [[array of tables]] header and return the table that following keys belong to
+func (p *tomlParser) parseTableHeader(root map[string]any) (map[string]any, error) {
+	isArray := p.consume("[[")
+	if !isArray {
+		p.pos++
+	}
+
+	p.skipWhitespace()
+	keys, err := p.parseKey()
+	if err != nil {
+		return nil, err
+	}
+	p.skipWhitespace()
+
+	closing := "]"
+	if isArray {
+		closing = "]]"
+	}
+	if !p.consume(closing) {
+		return nil, p.errorf("expected %q after table name", closing)
+	}
+
+	table := root
+	for i, key := range keys {
+		last := i == len(keys)-1
+		existing, exists := table[key]
+
+		switch value := existing.(type) {
+		case map[string]any:
+			if last && isArray {
+				return nil, p.errorf("key %q is already defined as a table", key)
+			}
+			table = value
+
+		case []any:
+			tables, ok := tomlTables(value)
+			if !ok {
+				return nil, p.errorf("key %q is already defined as an array", key)
+			}
+			if last && isArray {
+				next := make(map[string]any)
+				table[key] = append(value, next)
+				return next, nil
+			}
+			if last {
+				return nil, p.errorf("key %q is already defined as an array of tables", key)
+			}
+			table = tables[len(tables)-1]
+
+		default:
+			if exists {
+				return nil, p.errorf("key %q is already defined as a value", key)
+			}
+
+			next := make(map[string]any)
+			if last && isArray {
+				table[key] = []any{next}
+				return next, nil
+			}
+			table[key] = next
+			table = next
+		}
+	}
+
+	path := strings.Join(keys, "\x00")
+	if p.defined[path] {
+		return nil, p.errorf("table [%s] is defined more than once", strings.Join(keys, "."))
+	}
+	p.defined[path] = true
+
+	return table, nil
+}
+
+// Returns the values of an array of tables, or false if any value is not a table
+func tomlTables(values []any) ([]map[string]any, bool) {
+	var tables []map[string]any
+	for _, value := range values {
+		table, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		tables = append(tables, table)
+	}
+	return tables, len(tables) > 0
+}
+
+func (p *tomlParser) parseKeyValue(table map[string]any) error {
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+
+	p.skipWhitespace()
+	if !p.consume("=") {
+		return p.errorf("expected '=' after key %q", strings.Join(keys, "."))
+	}
+	p.skipWhitespace()
+
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys[:len(keys)-1] {
+		existing, exists := table[key]
+		if !exists {
+			next := make(map[string]any)
+			table[key] = next
+			table = next
+		} else if next, ok := existing.(map[string]any); ok {
+			table = next
+		} else {
+			return p.errorf("key %q is already defined as a value", key)
+		}
+	}
+
+	key := keys[len(keys)-1]
+	if _, exists := table[key]; exists {
+		return p.errorf("duplicate key %q", strings.Join(keys, "."))
+	}
+	table[key] = value
+
+	return nil
+}
+
+// Parse a bare, quoted or dotted key
+func (p *tomlParser) parseKey() ([]string, error) {
+	var keys []string
+
+	for {
+		var key string
+		if p.pos < len(p.src) && (p.src[p.pos] == '"' || p.src[p.pos] == '\'') {
+			var err error
+			if key, err = p.parseString(); err != nil {
+				return nil, err
+			}
+		} else {
+			start := p.pos
+			for p.pos < len(p.src) && strings.IndexByte(tomlBareKeyChars, p.src[p.pos]) >= 0 {
+				p.pos++
+			}
+			if p.pos == start {
+				return nil, p.errorf("expected a key")
+			}
+			key = p.src[start:p.pos]
+		}
+		keys = append(keys, key)
+
+		p.skipWhitespace()
+		if !p.consume(".") {
+			return keys, nil
+		}
+		p.skipWhitespace()
+	}
+}
+
+func (p *tomlParser) parseValue() (any, error) {
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("expected a value")
+	}
+
+	switch p.src[p.pos] {
+	case '"', '\'':
+		return p.parseString()
+	case '[':
+		return p.parseArray()
+	case '{':
+		return p.parseInlineTable()
+	}
+
+	start := p.pos
+	p.skipValue()
+
+	// Dates and times may be separated by a space
+	token := p.src[start:p.pos]
+	if tomlDatePattern.MatchString(token) && p.pos+3 < len(p.src) &&
+		p.src[p.pos] == ' ' && IsNumeric(rune(p.src[p.pos+1])) && IsNumeric(rune(p.src[p.pos+2])) && p.src[p.pos+3] == ':' {
+		p.pos++
+		p.skipValue()
+		token = p.src[start:p.pos]
+	}
+
+	value, ok := resolveTOMLScalar(token)
+	if !ok {
+		p.pos = start
+		if token == "" {
+			return nil, p.errorf("expected a value")
+		}
+		return nil, p.errorf("invalid value %q", token)
+	}
+	return value, nil
+}
+
+// Advance to the end of an unquoted value
+func (p *tomlParser) skipValue() {
+	for p.pos < len(p.src) && strings.IndexByte(tomlValueEnd, p.src[p.pos]) < 0 {
+		p.pos++
+	}
+}
+
+func resolveTOMLScalar(token string) (any, bool) {
+	switch token {
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	case "inf", "+inf":
+		return math.Inf(1), true
+	case "-inf":
+		return math.Inf(-1), true
+	case "nan", "+nan", "-nan":
+		return math.NaN(), true
+	}
+
+	if len(token) > 2 && token[0] == '0' && strings.IndexByte("xob", token[1]) >= 0 {
+		if value, err := strconv.ParseInt(token, 0, 0); err == nil {
+			return int(value), true
+		}
+		return nil, false
+	}
+
+	if tomlIntPattern.MatchString(token) {
+		value, err := strconv.ParseInt(strings.ReplaceAll(token, "_", ""), 10, 0)
+		return int(value), err == nil
+	}
+	if tomlFloatPattern.MatchString(token) {
+		value, err := strconv.ParseFloat(strings.ReplaceAll(token, "_", ""), 64)
+		return value, err == nil
+	}
+
+	if tomlTimePattern.MatchString(token) {
+		return token, true
+	}
+	for _, layout := range tomlTimeLayouts {
+		if value, err := time.Parse(layout, token); err == nil {
+			return value, true
+		}
+	}
+
+	return nil, false
+}
+
+// Parse a basic, literal or multi-line string
+func (p *tomlParser) parseString() (string, error) {
+	quote := p.src[p.pos]
+	delimiter := strings.Repeat(string(quote), 3)
+	multiline := p.consume(delimiter)
+	if multiline {
+		// A newline immediately after the opening delimiter is trimmed
+		p.consume("\n")
+	} else {
+		p.pos++
+		delimiter = string(quote)
+	}
+
+	var result strings.Builder
+	for {
+		if p.pos >= len(p.src) || (!multiline && p.src[p.pos] == '\n') {
+			return "", p.errorf("unterminated string")
+		}
+
+		if strings.HasPrefix(p.src[p.pos:], delimiter) {
+			p.pos += len(delimiter)
+			// Up to two quotes are allowed directly before the closing delimiter
+			for i := 0; multiline && i < 2 && p.pos < len(p.src) && p.src[p.pos] == quote; i++ {
+				result.WriteByte(quote)
+				p.pos++
+			}
+			return result.String(), nil
+		}
+
+		c := p.src[p.pos]
+		if c != '\\' || quote == '\'' {
+			result.WriteByte(c)
+			p.pos++
+			continue
+		}
+
+		if err := p.parseEscape(&result, multiline); err != nil {
+			return "", err
+		}
+	}
+}
+
+func (p *tomlParser) parseEscape(result *strings.Builder, multiline bool) error {
+	p.pos++
+	if p.pos >= len(p.src) {
+		return p.errorf("unterminated string")
+	}
+
+	c := p.src[p.pos]
+	p.pos++
+
+	switch c {
+	case 'b':
+		result.WriteByte('\b')
+	case 't':
+		result.WriteByte('\t')
+	case 'n':
+		result.WriteByte('\n')
+	case 'f':
+		result.WriteByte('\f')
+	case 'r':
+		result.WriteByte('\r')
+	case '"', '\\':
+		result.WriteByte(c)
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+		if p.pos+size > len(p.src) {
+			return p.errorf("invalid unicode escape")
+		}
+		code, err := strconv.ParseUint(p.src[p.pos:p.pos+size], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return p.errorf("invalid unicode escape \\%c%s", c, p.src[p.pos:p.pos+size])
+		}
+		result.WriteRune(rune(code))
+		p.pos += size
+	case ' ', '\t', '\n':
+		// A line ending backslash trims all whitespace up to the next non-whitespace character
+		rest := strings.TrimLeft(p.src[p.pos-1:], " \t")
+		if !multiline || !strings.HasPrefix(rest, "\n") {
+			return p.errorf("invalid escape sequence \\%c", c)
+		}
+		p.pos = len(p.src) - len(strings.TrimLeft(rest, " \t\n"))
+	default:
+		return p.errorf("invalid escape sequence \\%c", c)
+	}
+
+	return nil
+}
+
+// Parse an array, which may span multiple lines and contain comments
+func (p *tomlParser) parseArray() ([]any, error) {
+	p.pos++
+	array := []any{}
+
+	for {
+		p.skipBlankLines()
+		if p.consume("]") {
+			return array, nil
+		}
+
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, value)
+
+		p.skipBlankLines()
+		if !p.consume(",") && !strings.HasPrefix(p.src[p.pos:], "]") {
+			return nil, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+// Parse an inline table, which must fit on a single line
+func (p *tomlParser) parseInlineTable() (map[string]any, error) {
+	p.pos++
+	table := make(map[string]any)
+
+	p.skipWhitespace()
+	if p.consume("}") {
+		return table, nil
+	}
+
+	for {
+		p.skipWhitespace()
+		if err := p.parseKeyValue(table); err != nil {
+			return nil, err
+		}
+
+		p.skipWhitespace()
+		if p.consume("}") {
+			return table, nil
+		}
+		if !p.consume(",") {
+			return nil, p.errorf("expected ',' or '}' in inline table")
+		}
+	}
+}
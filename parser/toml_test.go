@@ -0,0 +1,130 @@
+package parser
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTOML(t *testing.T) {
+	const src = `# Page metadata
+title = "Apple \"Pie\" \u00e9"
+path = 'C:\recipes\pie'
+servings = 8
+big = 1_000_000
+hex = 0xff
+rating = 4.5
+ratio = 1e-2
+best = inf
+draft = false
+date = 2024-03-01
+updated = 2024-03-02 10:30:00Z
+local = 2024-03-02T10:30:00
+bake-time = 01:15:00
+tags = [
+  "dessert", # comment
+  'baking',
+]
+matrix = [[1, 2], [3]]
+"quoted key" = "value"
+author.name = "Jane"
+author.links = { site = "https://jane.example", label = "Home" }
+description = """
+A classic recipe, \
+  on one line."""
+notes = '''
+Line 1
+  Line 2'''
+
+[params]
+colour = "red"
+
+[params.nested]
+enabled = true
+
+[[ingredients]]
+name = "apples"
+
+[[ingredients]]
+name = "sugar"
+grams = 200
+`
+
+	expected := Metadata{
+		"title":      `Apple "Pie" é`,
+		"path":       `C:\recipes\pie`,
+		"servings":   8,
+		"big":        1000000,
+		"hex":        255,
+		"rating":     4.5,
+		"ratio":      0.01,
+		"best":       math.Inf(1),
+		"draft":      false,
+		"date":       time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		"updated":    time.Date(2024, 3, 2, 10, 30, 0, 0, time.UTC),
+		"local":      time.Date(2024, 3, 2, 10, 30, 0, 0, time.UTC),
+		"bake-time":  "01:15:00",
+		"tags":       []any{"dessert", "baking"},
+		"matrix":     []any{[]any{1, 2}, []any{3}},
+		"quoted key": "value",
+		"author": map[string]any{
+			"name":  "Jane",
+			"links": map[string]any{"site": "https://jane.example", "label": "Home"},
+		},
+		"description": "A classic recipe, on one line.",
+		"notes":       "Line 1\n  Line 2",
+		"params": map[string]any{
+			"colour": "red",
+			"nested": map[string]any{"enabled": true},
+		},
+		"ingredients": []any{
+			map[string]any{"name": "apples"},
+			map[string]any{"name": "sugar", "grams": 200},
+		},
+	}
+
+	result, err := ParseTOML(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, value := range expected {
+		if !reflect.DeepEqual(result[key], value) {
+			t.Fatalf("Key %q\nExpected:\n%#v\nResult:\n%#v", key, value, result[key])
+		}
+	}
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d keys, got %d:\n%#v", len(expected), len(result), result)
+	}
+}
+
+func TestTOMLErrors(t *testing.T) {
+	tests := map[string]int{
+		"a = 1\na = 2":                 2,
+		"a = 1\n[a]":                   2,
+		"[a]\nb = 1\n[a]":              3,
+		"a = \"unclosed\nb = 1":        1,
+		"a = [1, 2\nb = 1":             2,
+		"a = 1 b = 2":                  1,
+		"a = 01":                       1,
+		"a = \"\\q\"":                  1,
+		"a = {b = 1\n}":                1,
+		"\n\nkey":                      3,
+		"a = [1]\n[[a]]":               2,
+		"a = \"\"\"\nunterminated\n\n": 4,
+	}
+
+	for src, line := range tests {
+		t.Run(src, func(t *testing.T) {
+			_, err := ParseTOML(src)
+			syntaxErr, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("Expected a *SyntaxError, got %v", err)
+			}
+			if syntaxErr.Line != line {
+				t.Fatalf("Expected error on line %d, got %s", line, syntaxErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,571 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A subset of YAML 1.2 covering what is commonly found in frontmatter:
+// block mappings and sequences, flow collections, quoted and plain scalars,
+// literal and folded block scalars and comments.
+// Anchors, aliases, tags and multi-line plain scalars are not supported.
+// See: https://yaml.org/spec/1.2.2/
+
+// A syntax error with the line it was found on
+type SyntaxError struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+}
+
+var (
+	yamlIntPattern   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlFloatPattern = regexp.MustCompile(`^[-+]?(?:\.[0-9]+|[0-9]+(?:\.[0-9]*)?)(?:[eE][-+]?[0-9]+)?$`)
+)
+
+var yamlTimeLayouts = []string{
+	"2006-01-02",
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -07:00",
+}
+
+// Deeper block collections are rejected rather than parsed in quadratic time
+const maxYAMLNesting = 64
+
+type yamlParser struct {
+	lines []string
+	pos   int
+	depth int
+}
+
+// Parse a YAML document whose top level is a mapping
+// Values are string, int, float64, bool, time.Time, []any, map[string]any or nil
+func ParseYAML(src string) (Metadata, error) {
+	p := yamlParser{lines: strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")}
+	metadata := make(Metadata)
+
+	indent, err := p.skipBlank()
+	if err != nil || indent < 0 {
+		return metadata, err
+	}
+	if indent > 0 {
+		return metadata, p.errorf("unexpected indentation")
+	}
+
+	value, err := p.parseNode(0)
+	if err != nil {
+		return metadata, err
+	}
+
+	if _, err := p.skipBlank(); err != nil {
+		return metadata, err
+	} else if p.pos < len(p.lines) {
+		return metadata, p.errorf("unexpected content after the end of the document")
+	}
+
+	values, ok := value.(map[string]any)
+	if !ok {
+		return metadata, &SyntaxError{Line: 1, Message: "top level must be a mapping of keys to values"}
+	}
+	for key, value := range values {
+		metadata[key] = value
+	}
+
+	return metadata, nil
+}
+
+func (p *yamlParser) errorf(format string, a ...any) error {
+	return &SyntaxError{Line: p.pos + 1, Message: fmt.Sprintf(format, a...)}
+}
+
+// Advance past blank and comment lines
+// Returns the indentation of the next line, or -1 at the end of the document
+func (p *yamlParser) skipBlank() (int, error) {
+	for ; p.pos < len(p.lines); p.pos++ {
+		line := p.lines[p.pos]
+		text := strings.TrimLeft(line, " ")
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		if text[0] == '\t' {
+			return -1, p.errorf("tabs are not allowed for indentation")
+		}
+		return len(line) - len(text), nil
+	}
+	return -1, nil
+}
+
+// The current line with indentation and any trailing comment removed
+func (p *yamlParser) text() string {
+	return stripYAMLComment(strings.TrimLeft(p.lines[p.pos], " "))
+}
+
+// Parse the block node starting on the current line, which is indented by indent
+func (p *yamlParser) parseNode(indent int) (any, error) {
+	if p.depth++; p.depth > maxYAMLNesting {
+		return nil, p.errorf("collections are nested more than %d levels deep", maxYAMLNesting)
+	}
+	defer func() { p.depth-- }()
+
+	text := p.text()
+
+	if text == "-" || strings.HasPrefix(text, "- ") {
+		return p.parseSequence(indent)
+	}
+
+	if _, _, ok, err := cutYAMLKey(text); err != nil {
+		return nil, p.errorf("%s", err)
+	} else if ok {
+		return p.parseMapping(indent)
+	}
+
+	if text[0] == '|' || text[0] == '>' {
+		return p.parseBlockScalar(text, indent-1)
+	}
+
+	p.pos++
+	value, err := parseYAMLValue(text)
+	if err != nil {
+		p.pos--
+		return nil, p.errorf("%s", err)
+	}
+	return value, nil
+}
+
+func (p *yamlParser) parseMapping(indent int) (map[string]any, error) {
+	mapping := make(map[string]any)
+
+	for {
+		lineIndent, err := p.skipBlank()
+		if err != nil {
+			return nil, err
+		}
+		if lineIndent < indent {
+			return mapping, nil
+		}
+		if lineIndent > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+
+		key, rest, ok, err := cutYAMLKey(p.text())
+		if err != nil {
+			return nil, p.errorf("%s", err)
+		} else if !ok {
+			return nil, p.errorf("expected a key followed by ':'")
+		}
+		if _, exists := mapping[key]; exists {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+
+		var value any
+		if rest == "" {
+			value, err = p.parseNested(indent, true)
+		} else if rest[0] == '|' || rest[0] == '>' {
+			value, err = p.parseBlockScalar(rest, indent)
+		} else {
+			value, err = parseYAMLValue(rest)
+			if err != nil {
+				err = p.errorf("%s", err)
+			}
+			p.pos++
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		mapping[key] = value
+	}
+}
+
+func (p *yamlParser) parseSequence(indent int) ([]any, error) {
+	sequence := []any{}
+
+	for {
+		lineIndent, err := p.skipBlank()
+		if err != nil {
+			return nil, err
+		}
+		if lineIndent < indent {
+			return sequence, nil
+		}
+		if lineIndent > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+
+		text := p.text()
+		if text != "-" && !strings.HasPrefix(text, "- ") {
+			return sequence, nil
+		}
+
+		var value any
+		item := strings.TrimLeft(text[1:], " ")
+		if item == "" {
+			value, err = p.parseNested(indent, false)
+		} else {
+			// Parse the rest of the line as if it started on its own line at the same column
+			itemIndent := indent + len(text) - len(item)
+			p.lines[p.pos] = strings.Repeat(" ", itemIndent) + item
+			value, err = p.parseNode(itemIndent)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		sequence = append(sequence, value)
+	}
+}
+
+// Parse the value of a key or sequence item that is empty on its own line
+// Sequences may be nested under a mapping key at the same indentation
+func (p *yamlParser) parseNested(indent int, allowSequence bool) (any, error) {
+	p.pos++
+	nestedIndent, err := p.skipBlank()
+	if err != nil {
+		return nil, err
+	}
+
+	if nestedIndent > indent {
+		return p.parseNode(nestedIndent)
+	}
+
+	if nestedIndent == indent && allowSequence {
+		if text := p.text(); text == "-" || strings.HasPrefix(text, "- ") {
+			return p.parseSequence(indent)
+		}
+	}
+
+	return nil, nil
+}
+
+// Parse a literal (|) or folded (>) block scalar whose content is indented more than parentIndent
+func (p *yamlParser) parseBlockScalar(header string, parentIndent int) (string, error) {
+	folded := header[0] == '>'
+	chomping := byte(0)
+	contentIndent := -1
+
+	for _, c := range []byte(strings.TrimSpace(header[1:])) {
+		switch {
+		case (c == '-' || c == '+') && chomping == 0:
+			chomping = c
+		case c >= '1' && c <= '9' && contentIndent < 0:
+			contentIndent = parentIndent + 1 + int(c-'1')
+		default:
+			return "", p.errorf("invalid block scalar header %q", header)
+		}
+	}
+
+	var lines []string
+	for p.pos++; p.pos < len(p.lines); p.pos++ {
+		line := p.lines[p.pos]
+		text := strings.TrimLeft(line, " ")
+		indent := len(line) - len(text)
+
+		if text == "" {
+			lines = append(lines, "")
+			continue
+		}
+		if contentIndent < 0 {
+			contentIndent = indent
+		}
+		if indent < contentIndent || indent <= parentIndent {
+			break
+		}
+
+		lines = append(lines, line[contentIndent:])
+	}
+
+	var trailing int
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+	if len(lines) == 0 {
+		return "", nil
+	}
+
+	var result strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			prev := lines[i-1]
+			moreIndented := strings.HasPrefix(line, " ") || strings.HasPrefix(prev, " ")
+			// Folding joins adjacent lines with a space and drops the first line break before blank lines
+			if !folded || moreIndented || prev == "" {
+				result.WriteByte('\n')
+			} else if line != "" {
+				result.WriteByte(' ')
+			}
+		}
+		result.WriteString(line)
+	}
+
+	switch chomping {
+	case '-':
+	case '+':
+		result.WriteString(strings.Repeat("\n", trailing+1))
+	default:
+		result.WriteByte('\n')
+	}
+
+	return result.String(), nil
+}
+
+// Remove a comment, which starts with a # at the start of the text or after whitespace outside of quotes
+func stripYAMLComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || strings.IndexByte(" \t[{,:", text[i-1]) >= 0):
+			quote = c
+		case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return strings.TrimRight(text[:i], " \t")
+		}
+	}
+
+	return strings.TrimRight(text, " \t")
+}
+
+// Split a mapping entry into its key and the text of its value
+// ok is false if text is not a mapping entry
+func cutYAMLKey(text string) (string, string, bool, error) {
+	if text == "" || text[0] == '[' || text[0] == '{' || text[0] == '-' && (len(text) == 1 || text[1] == ' ') {
+		return "", "", false, nil
+	}
+
+	if text[0] == '"' || text[0] == '\'' {
+		key, rest, err := cutYAMLQuoted(text)
+		if err != nil {
+			return "", "", false, err
+		}
+		rest = strings.TrimLeft(rest, " ")
+		if rest != ":" && !strings.HasPrefix(rest, ": ") {
+			return "", "", false, nil
+		}
+		return key, strings.TrimSpace(rest[1:]), true, nil
+	}
+
+	colon := strings.Index(text, ": ")
+	if colon < 0 && strings.HasSuffix(text, ":") {
+		colon = len(text) - 1
+	}
+	if colon < 0 {
+		return "", "", false, nil
+	}
+
+	key := strings.TrimRight(text[:colon], " ")
+	if key == "" {
+		return "", "", false, fmt.Errorf("empty mapping key")
+	}
+	return key, strings.TrimSpace(text[colon+1:]), true, nil
+}
+
+// Split a quoted scalar at the start of text from the text following it
+func cutYAMLQuoted(text string) (string, string, error) {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] == quote && quote == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == quote:
+			if quote == '\'' {
+				return strings.ReplaceAll(text[1:i], "''", "'"), text[i+1:], nil
+			}
+
+			value, err := strconv.Unquote(strings.ReplaceAll(text[:i+1], `\/`, "/"))
+			if err != nil {
+				return "", "", fmt.Errorf("invalid escape sequence in %s", text[:i+1])
+			}
+			return value, text[i+1:], nil
+		}
+	}
+
+	return "", "", fmt.Errorf("unterminated quoted string")
+}
+
+// Parse a value that fits on a single line
+func parseYAMLValue(text string) (any, error) {
+	switch text[0] {
+	case '[', '{':
+		value, rest, err := parseYAMLFlow(text)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(rest) != "" {
+			return nil, fmt.Errorf("unexpected %q after flow collection", strings.TrimSpace(rest))
+		}
+		return value, nil
+
+	case '"', '\'':
+		value, rest, err := cutYAMLQuoted(text)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(rest) != "" {
+			return nil, fmt.Errorf("unexpected %q after quoted string", strings.TrimSpace(rest))
+		}
+		return value, nil
+
+	case '&', '*', '!':
+		return nil, fmt.Errorf("anchors, aliases and tags are not supported")
+	}
+
+	return resolveYAMLScalar(text), nil
+}
+
+// Parse a flow sequence or mapping at the start of text, returning the text after it
+func parseYAMLFlow(text string) (any, string, error) {
+	closing := byte(']')
+	if text[0] == '{' {
+		closing = '}'
+	}
+
+	sequence := []any{}
+	mapping := make(map[string]any)
+
+	rest := strings.TrimLeft(text[1:], " ")
+	for {
+		if rest == "" {
+			return nil, "", fmt.Errorf("unterminated flow collection, expected '%c'", closing)
+		}
+		if rest[0] == closing {
+			break
+		}
+
+		var key string
+		if closing == '}' {
+			var err error
+			var value any
+			value, rest, err = parseYAMLFlowItem(rest, true)
+			if err != nil {
+				return nil, "", err
+			}
+			key = fmt.Sprint(value)
+
+			rest = strings.TrimLeft(rest, " ")
+			if !strings.HasPrefix(rest, ":") {
+				return nil, "", fmt.Errorf("expected ':' after flow mapping key %q", key)
+			}
+			rest = strings.TrimLeft(rest[1:], " ")
+		}
+
+		value, remaining, err := parseYAMLFlowItem(rest, false)
+		if err != nil {
+			return nil, "", err
+		}
+		rest = strings.TrimLeft(remaining, " ")
+
+		if closing == '}' {
+			if _, exists := mapping[key]; exists {
+				return nil, "", fmt.Errorf("duplicate key %q", key)
+			}
+			mapping[key] = value
+		} else {
+			sequence = append(sequence, value)
+		}
+
+		if strings.HasPrefix(rest, ",") {
+			rest = strings.TrimLeft(rest[1:], " ")
+		} else if rest == "" || rest[0] != closing {
+			return nil, "", fmt.Errorf("expected ',' or '%c' in flow collection", closing)
+		}
+	}
+
+	if closing == '}' {
+		return mapping, rest[1:], nil
+	}
+	return sequence, rest[1:], nil
+}
+
+// Parse a single item of a flow collection, returning the text after it
+func parseYAMLFlowItem(text string, isKey bool) (any, string, error) {
+	if text == "" {
+		return nil, "", fmt.Errorf("unterminated flow collection")
+	}
+
+	switch text[0] {
+	case '[', '{':
+		return parseYAMLFlow(text)
+	case '"', '\'':
+		return cutYAMLQuoted(text)
+	case '&', '*', '!':
+		return nil, "", fmt.Errorf("anchors, aliases and tags are not supported")
+	}
+
+	end := strings.IndexAny(text, ",]}")
+	if end < 0 {
+		end = len(text)
+	}
+	if isKey {
+		if colon := strings.IndexByte(text[:end], ':'); colon >= 0 {
+			end = colon
+		}
+	}
+
+	return resolveYAMLScalar(strings.TrimSpace(text[:end])), text[end:], nil
+}
+
+// Resolve the type of a plain scalar
+func resolveYAMLScalar(text string) any {
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN()
+	}
+
+	if yamlIntPattern.MatchString(text) {
+		if value, err := strconv.ParseInt(text, 10, 0); err == nil {
+			return int(value)
+		}
+	}
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0o") {
+		if value, err := strconv.ParseInt(text, 0, 0); err == nil {
+			return int(value)
+		}
+	}
+	if yamlFloatPattern.MatchString(text) {
+		if value, err := strconv.ParseFloat(text, 64); err == nil {
+			return value
+		}
+	}
+
+	if len(text) >= len("2006-01-02") && IsNumeric(rune(text[0])) {
+		for _, layout := range yamlTimeLayouts {
+			if value, err := time.Parse(layout, text); err == nil {
+				return value
+			}
+		}
+	}
+
+	return text
+}
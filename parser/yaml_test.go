@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseYAML(t *testing.T) {
+	const src = `# Page metadata
+title: "Apple \"Pie\""
+subtitle: It's the best # a comment
+description: >
+  A classic recipe,
+  folded onto one line.
+
+  With a second paragraph.
+notes: |-
+  Line 1
+    Line 2
+servings: 8
+rating: 4.5
+draft: false
+published: null
+date: 2024-03-01
+updated: 2024-03-02T10:30:00Z
+url: https://example.com/pie#top
+tags: [dessert, 'baking', "fruit"]
+empty: []
+author:
+  name: Jane
+  links:
+    - site: https://jane.example
+      label: Home
+    - site: https://jane.example/feed
+ingredients:
+- apples
+- - nested
+  - list
+- {name: sugar, grams: 200}
+`
+
+	expected := Metadata{
+		"title":       `Apple "Pie"`,
+		"subtitle":    "It's the best",
+		"description": "A classic recipe, folded onto one line.\nWith a second paragraph.\n",
+		"notes":       "Line 1\n  Line 2",
+		"servings":    8,
+		"rating":      4.5,
+		"draft":       false,
+		"published":   nil,
+		"date":        time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		"updated":     time.Date(2024, 3, 2, 10, 30, 0, 0, time.UTC),
+		"url":         "https://example.com/pie#top",
+		"tags":        []any{"dessert", "baking", "fruit"},
+		"empty":       []any{},
+		"author": map[string]any{
+			"name": "Jane",
+			"links": []any{
+				map[string]any{"site": "https://jane.example", "label": "Home"},
+				map[string]any{"site": "https://jane.example/feed"},
+			},
+		},
+		"ingredients": []any{
+			"apples",
+			[]any{"nested", "list"},
+			map[string]any{"name": "sugar", "grams": 200},
+		},
+	}
+
+	result, err := ParseYAML(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, value := range expected {
+		if !reflect.DeepEqual(result[key], value) {
+			t.Fatalf("Key %q\nExpected:\n%#v\nResult:\n%#v", key, value, result[key])
+		}
+	}
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d keys, got %d:\n%#v", len(expected), len(result), result)
+	}
+}
+
+func TestYAMLErrors(t *testing.T) {
+	tests := map[string]int{
+		"title: a\n  nested: b":        2,
+		"title: a\ntitle: b":           2,
+		"title: a\njust text":          2,
+		"tags: [a, b":                  1,
+		"title: \"unclosed":            1,
+		"a: 1\nb: *alias":              2,
+		"a:\n\t- tab":                  2,
+		"- a\n- b":                     1,
+		"a: {x: 1, x: 2}":              1,
+		"a: 1\nb:\n  - x\n  y: z":      4,
+		"a: |\n  text\n b: misaligned": 3,
+		strings.Repeat("- ", 100):      1,
+	}
+
+	for src, line := range tests {
+		t.Run(src, func(t *testing.T) {
+			_, err := ParseYAML(src)
+			syntaxErr, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("Expected a *SyntaxError, got %v", err)
+			}
+			if syntaxErr.Line != line {
+				t.Fatalf("Expected error on line %d, got %s", line, syntaxErr)
+			}
+		})
+	}
+}